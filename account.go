@@ -1,8 +1,250 @@
 package main
 
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
 // Account contains information about a licensor account.
 type Account interface {
 	API() string
 	LicensorID() string
 	Token() string
 }
+
+// accountRecord is the stored form of an Account. Its token is sealed
+// with the user's passphrase, and available only after unlock.
+type accountRecord struct {
+	APIURL      string  `json:"api"`
+	ID          string  `json:"licensorID"`
+	SealedToken *sealed `json:"token"`
+	secret      string
+}
+
+func (a *accountRecord) API() string {
+	return a.APIURL
+}
+
+func (a *accountRecord) LicensorID() string {
+	return a.ID
+}
+
+func (a *accountRecord) Token() string {
+	return a.secret
+}
+
+func (a *accountRecord) unlock(passphrase string) error {
+	if a.SealedToken == nil {
+		// Saved before tokens were encrypted.
+		return nil
+	}
+	opened, err := a.SealedToken.open(passphrase)
+	if err != nil {
+		return err
+	}
+	a.secret = string(opened)
+	return nil
+}
+
+// seal returns the stored form of the account, with its token sealed
+// by a passphrase.
+func (a *accountRecord) seal(passphrase string) ([]byte, error) {
+	sealedToken, err := seal(passphrase, []byte(a.secret))
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(accountRecord{
+		APIURL:      a.APIURL,
+		ID:          a.ID,
+		SealedToken: sealedToken,
+	}, "", "  ")
+}
+
+// unlockAccount decrypts the token of an account read from the
+// configuration directory.
+func unlockAccount(env *environment, account Account) error {
+	record, ok := account.(*accountRecord)
+	if !ok || record.secret != "" {
+		return nil
+	}
+	passphrase, err := readPassphrase(env, "Passphrase")
+	if err != nil {
+		return err
+	}
+	return record.unlock(passphrase)
+}
+
+func readAccountRecord(filePath string) (*accountRecord, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		API        string          `json:"api"`
+		LicensorID string          `json:"licensorID"`
+		Token      json.RawMessage `json:"token"`
+	}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, &fileError{filePath, err}
+	}
+	account := accountRecord{APIURL: raw.API, ID: raw.LicensorID}
+	if len(raw.Token) != 0 && raw.Token[0] == '"' {
+		err = json.Unmarshal(raw.Token, &account.secret)
+	} else if len(raw.Token) != 0 {
+		err = json.Unmarshal(raw.Token, &account.SealedToken)
+	}
+	if err != nil {
+		return nil, &fileError{filePath, err}
+	}
+	if account.APIURL == "" || account.ID == "" ||
+		(account.SealedToken == nil && account.secret == "") {
+		return nil, &fileError{filePath, errors.New("missing api, licensorID, or token")}
+	}
+	return &account, nil
+}
+
+const defaultAPI = "https://api.licensezero.com"
+
+func accountPath(configPath string, api string, licensorID string) string {
+	host := api
+	if parsed, err := url.Parse(api); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+	host = strings.Replace(host, ":", "_", -1)
+	return path.Join(configPath, "accounts", host+"_"+licensorID+".json")
+}
+
+func writeAccount(configPath string, account *accountRecord, passphrase string) error {
+	data, err := account.seal(passphrase)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Join(configPath, "accounts"), 0700)
+	if err != nil {
+		return err
+	}
+	filePath := accountPath(configPath, account.APIURL, account.ID)
+	return writeFileAtomically(filePath, data, 0600)
+}
+
+func validateAPI(api string) error {
+	parsed, err := url.Parse(api)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return errors.New("API must be an HTTP or HTTPS URL")
+	}
+	if parsed.Host == "" {
+		return errors.New("API URL must have a host")
+	}
+	return nil
+}
+
+const loginUsage = `Save credentials for a licensor account.
+
+Usage:
+  licensezero login --licensor ID [--api URL]
+
+The API token is prompted for without echoing, or read from standard
+input if it isn't a terminal. It is stored encrypted with a
+passphrase, read from ` + passphraseEnvironmentVariable + ` or prompted for.
+`
+
+var loginCommand = subcommand{
+	Summary: "Save licensor account credentials.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("login", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, loginUsage) }
+		apiFlag := flagSet.String("api", defaultAPI, "")
+		licensorID := flagSet.String("licensor", "", "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 || *licensorID == "" {
+			flagSet.Usage()
+			return 1
+		}
+		api := strings.TrimRight(*apiFlag, "/")
+		if err := validateAPI(api); err != nil {
+			fmt.Fprintln(env.Stderr, "Invalid API:", err)
+			return 1
+		}
+		if !validUUID.MatchString(*licensorID) {
+			fmt.Fprintln(env.Stderr, "Invalid licensor ID:", *licensorID)
+			return 1
+		}
+		token, err := readSecret(env, "", "Token")
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read token:", err)
+			return 1
+		}
+		token = strings.TrimSpace(token)
+		if token == "" {
+			fmt.Fprintln(env.Stderr, "Empty token.")
+			return 1
+		}
+		passphrase, err := readStorePassphrase(env)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read passphrase:", err)
+			return 1
+		}
+		err = writeAccount(env.Config, &accountRecord{
+			APIURL: api,
+			ID:     *licensorID,
+			secret: token,
+		}, passphrase)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not write account:", err)
+			return 1
+		}
+		return 0
+	},
+}
+
+const logoutUsage = `Remove credentials for licensor accounts.
+
+Usage:
+  licensezero logout --licensor ID [--api URL]
+  licensezero logout --all
+`
+
+var logoutCommand = subcommand{
+	Summary: "Remove licensor account credentials.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("logout", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, logoutUsage) }
+		api := flagSet.String("api", defaultAPI, "")
+		licensorID := flagSet.String("licensor", "", "")
+		all := flagSet.Bool("all", false, "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 || (*licensorID == "") == !*all {
+			flagSet.Usage()
+			return 1
+		}
+		if *all {
+			err := os.RemoveAll(path.Join(env.Config, "accounts"))
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not remove accounts:", err)
+				return 1
+			}
+			return 0
+		}
+		err := os.Remove(accountPath(env.Config, strings.TrimRight(*api, "/"), *licensorID))
+		if err != nil {
+			if os.IsNotExist(err) {
+				fmt.Fprintln(env.Stderr, "Not logged in as", *licensorID)
+			} else {
+				fmt.Fprintln(env.Stderr, "Could not remove account:", err)
+			}
+			return 1
+		}
+		return 0
+	},
+}
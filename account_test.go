@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestLoginAndLogout(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		licensorID := "59e70a4d-ffee-4e9d-a526-7a9ff9161664"
+		env, _, stderr := newTestEnvironment(directory, "secret-token\npassphrase\npassphrase\n", nil)
+		if run([]string{"login", "--licensor", licensorID}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		filePath := accountPath(directory, defaultAPI, licensorID)
+		info, err := os.Stat(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("wrote account with mode %v", info.Mode().Perm())
+		}
+
+		err = ioutil.WriteFile(path.Join(directory, "accounts", "malformed.json"), []byte(`{"api":`), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		accounts, accountErrors, err := ReadAccounts(directory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(accounts) != 1 {
+			t.Fatal("did not read account")
+		}
+		if len(accountErrors) != 1 {
+			t.Error("did not report malformed account")
+		}
+		account := accounts[0]
+		if account.API() != defaultAPI || account.LicensorID() != licensorID {
+			t.Error("did not read account values")
+		}
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "secret-token") {
+			t.Error("stored token in plaintext")
+		}
+		env, _, _ = newTestEnvironment(directory, "passphrase\n", nil)
+		err = unlockAccount(env, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account.Token() != "secret-token" {
+			t.Error("did not decrypt token")
+		}
+
+		item := Item{API: defaultAPI}
+		item.Offer.LicensorID = licensorID
+		if !ownProject(&item, accounts) {
+			t.Error("did not recognize own project")
+		}
+		item.Offer.LicensorID = "d56ee0a6-4ed3-4793-9485-6135644c158f"
+		if ownProject(&item, accounts) {
+			t.Error("recognized another licensor's project")
+		}
+
+		env, _, stderr = newTestEnvironment(directory, "", nil)
+		if run([]string{"logout", "--licensor", licensorID}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+			t.Error("did not remove account")
+		}
+	})
+}
+
+func TestRekey(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		licensorID := "59e70a4d-ffee-4e9d-a526-7a9ff9161664"
+		oldPassphrase := map[string]string{passphraseEnvironmentVariable: "old"}
+		env, _, stderr := newTestEnvironment(directory, "secret-token\n", oldPassphrase)
+		if run([]string{"login", "--licensor", licensorID}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		env, _, stderr = newTestEnvironment(directory, "", oldPassphrase)
+		if run([]string{"keygen"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+
+		// Tokens saved in plaintext get encrypted, too.
+		legacyID := "d56ee0a6-4ed3-4793-9485-6135644c158f"
+		err := ioutil.WriteFile(
+			accountPath(directory, defaultAPI, legacyID),
+			[]byte(`{"api":"`+defaultAPI+`","licensorID":"`+legacyID+`","token":"legacy-token"}`),
+			0600,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		env, _, stderr = newTestEnvironment(directory, "", map[string]string{
+			passphraseEnvironmentVariable:    "wrong",
+			newPassphraseEnvironmentVariable: "new",
+		})
+		if run([]string{"rekey"}, env) == 0 {
+			t.Error("rekeyed with wrong passphrase")
+		}
+
+		env, _, stderr = newTestEnvironment(directory, "", map[string]string{
+			passphraseEnvironmentVariable:    "old",
+			newPassphraseEnvironmentVariable: "new",
+		})
+		if run([]string{"rekey"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+
+		if _, err := readSigningKey(directory, "default", "new"); err != nil {
+			t.Error("could not open key with new passphrase")
+		}
+		if _, err := readSigningKey(directory, "default", "old"); err == nil {
+			t.Error("opened key with old passphrase")
+		}
+		accounts, _, err := ReadAccounts(directory)
+		if err != nil || len(accounts) != 2 {
+			t.Fatal("could not read accounts")
+		}
+		for _, account := range accounts {
+			record := account.(*accountRecord)
+			if record.SealedToken == nil {
+				t.Error("did not encrypt plaintext token")
+			}
+			if err := record.unlock("new"); err != nil {
+				t.Error("could not open token with new passphrase")
+			}
+		}
+	})
+}
+
+func TestRekeySkipsStrayFiles(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		passphrase := map[string]string{passphraseEnvironmentVariable: "old"}
+		env, _, stderr := newTestEnvironment(directory, "secret-token\n", passphrase)
+		if run([]string{"login", "--licensor", "59e70a4d-ffee-4e9d-a526-7a9ff9161664"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		env, _, stderr = newTestEnvironment(directory, "", passphrase)
+		if run([]string{"keygen"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		writeTestFiles(t, directory, map[string]string{
+			"accounts/backup.json~":           "stray",
+			"keys/.default.json.123456":       "half written",
+			"keys/notes.txt":                  "stray",
+			"accounts/.interrupted.json.9876": "",
+		})
+
+		env, _, stderr = newTestEnvironment(directory, "", map[string]string{
+			passphraseEnvironmentVariable:    "old",
+			newPassphraseEnvironmentVariable: "new",
+		})
+		if run([]string{"rekey"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+
+		broken := path.Join(directory, "accounts", "broken.json")
+		writeTestFiles(t, directory, map[string]string{"accounts/broken.json": "{"})
+		env, _, stderr = newTestEnvironment(directory, "other-token\n", map[string]string{
+			passphraseEnvironmentVariable: "new",
+		})
+		if run([]string{"login", "--licensor", "d56ee0a6-4ed3-4793-9485-6135644c158f"}, env) != 0 {
+			t.Fatalf("login failed with an unreadable account file: %s", stderr.String())
+		}
+		env, _, stderr = newTestEnvironment(directory, "", map[string]string{
+			passphraseEnvironmentVariable:    "new",
+			newPassphraseEnvironmentVariable: "newer",
+		})
+		if run([]string{"rekey"}, env) == 0 {
+			t.Error("rekeyed with an unreadable account file")
+		}
+		if !strings.Contains(stderr.String(), broken) {
+			t.Errorf("stderr:\n%s", stderr.String())
+		}
+		if _, err := readSigningKey(directory, "default", "new"); err != nil {
+			t.Error("changed passphrase despite unreadable file")
+		}
+	})
+}
@@ -1,13 +1,56 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 )
 
+// apiClient makes requests to licensing APIs.
+var apiClient = newAPIClient(http.DefaultTransport)
+
+// newAPIClient returns a client that makes only HTTPS requests and
+// follows redirects only within the same origin.
+func newAPIClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport:     httpsOnly{transport},
+		CheckRedirect: checkAPIRedirect,
+	}
+}
+
+// httpsOnly refuses to make requests without TLS.
+type httpsOnly struct {
+	transport http.RoundTripper
+}
+
+func (h httpsOnly) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Scheme != "https" {
+		return nil, errors.New("refusing request without HTTPS: " + request.URL.String())
+	}
+	return h.transport.RoundTrip(request)
+}
+
+func checkAPIRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	from, to := via[0].URL, request.URL
+	if to.Scheme != from.Scheme || !strings.EqualFold(to.Host, from.Host) {
+		return errors.New("refusing redirect to another origin: " + to.Scheme + "://" + to.Host)
+	}
+	return nil
+}
+
+// GetOffer fetches an offer.
 func GetOffer(api string, offerID string) (offer Offer, err error) {
-	response, err := http.Get(api + "/offers/" + offerID)
+	response, err := apiClient.Get(api + "/offers/" + offerID)
 	if err != nil {
 		return
 	}
@@ -23,7 +66,291 @@ func GetOffer(api string, offerID string) (offer Offer, err error) {
 	}
 	offer, err = ParseOffer(unstructured)
 	if err != nil {
-		return
+		return offer, &invalidOfferError{err}
+	}
+	return
+}
+
+// invalidOfferError reports an offer from an API that doesn't conform
+// to the schema, as opposed to one the API couldn't find.
+type invalidOfferError struct {
+	Err error
+}
+
+func (e *invalidOfferError) Error() string {
+	return "invalid offer: " + e.Err.Error()
+}
+
+// offerBatchSize is the most offers to ask for in one batch lookup.
+const offerBatchSize = 100
+
+var (
+	batchSupportMutex sync.Mutex
+	// batchUnsupported records APIs that don't support batch lookup.
+	batchUnsupported = make(map[string]bool)
+)
+
+var errBatchUnsupported = errors.New("batch offer lookup not supported")
+
+// GetOffers fetches offers, in batches if the API supports it, and one
+// at a time if it doesn't. It returns the offers it found and an error
+// for each offer it couldn't get.
+func GetOffers(api string, offerIDs []string) (offers map[string]Offer, errs map[string]error) {
+	offers = make(map[string]Offer)
+	errs = make(map[string]error)
+	remaining := offerIDs
+	for len(remaining) != 0 {
+		batchSupportMutex.Lock()
+		unsupported := batchUnsupported[api]
+		batchSupportMutex.Unlock()
+		if unsupported {
+			break
+		}
+		batch := remaining
+		if len(batch) > offerBatchSize {
+			batch = batch[:offerBatchSize]
+		}
+		found, invalid, err := getOfferBatch(api, batch)
+		if err == errBatchUnsupported {
+			batchSupportMutex.Lock()
+			batchUnsupported[api] = true
+			batchSupportMutex.Unlock()
+			break
+		}
+		for _, offerID := range batch {
+			if err != nil {
+				errs[offerID] = err
+			} else if offer, ok := found[offerID]; ok {
+				offers[offerID] = offer
+			} else if invalidError, ok := invalid[offerID]; ok {
+				errs[offerID] = invalidError
+			} else {
+				errs[offerID] = errors.New("offer not found")
+			}
+		}
+		remaining = remaining[len(batch):]
+	}
+	for _, offerID := range remaining {
+		offer, err := GetOffer(api, offerID)
+		if err != nil {
+			errs[offerID] = err
+		} else {
+			offers[offerID] = offer
+		}
 	}
 	return
 }
+
+// getOfferBatch asks an API for several offers at once. It returns the
+// offers it found and an error for each offer that was invalid.
+func getOfferBatch(api string, offerIDs []string) (offers map[string]Offer, invalid map[string]error, err error) {
+	var responseBody struct {
+		Offers map[string]interface{} `json:"offers"`
+	}
+	err = postJSON(api+"/offers/lookup", "", map[string][]string{"offerIDs": offerIDs}, &responseBody)
+	if statusError, ok := err.(*apiStatusError); ok {
+		switch statusError.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			return nil, nil, errBatchUnsupported
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	offers = make(map[string]Offer)
+	invalid = make(map[string]error)
+	for offerID, unstructured := range responseBody.Offers {
+		offer, err := ParseOffer(unstructured)
+		if err != nil {
+			invalid[offerID] = &invalidOfferError{err}
+			continue
+		}
+		offers[offerID] = offer
+	}
+	return offers, invalid, nil
+}
+
+// OfferRequest describes a new offer to create.
+type OfferRequest struct {
+	LicensorID string         `json:"licensorID"`
+	URL        string         `json:"url"`
+	Pricing    PricingRequest `json:"pricing"`
+}
+
+// PricingRequest describes the prices of a new offer.
+type PricingRequest struct {
+	Single    Price  `json:"single"`
+	Relicense *Price `json:"relicense,omitempty"`
+}
+
+// CreateOffer creates an offer and returns its offerID.
+func CreateOffer(account Account, request OfferRequest) (offerID string, err error) {
+	var responseBody struct {
+		OfferID string `json:"offerID"`
+	}
+	err = postJSON(account.API()+"/offers", account.Token(), request, &responseBody)
+	if err != nil {
+		return
+	}
+	if !validUUID.MatchString(responseBody.OfferID) {
+		return "", errors.New("invalid offerID in response")
+	}
+	return responseBody.OfferID, nil
+}
+
+// OrderRequest describes licenses to buy from an API.
+type OrderRequest struct {
+	Licensee  Licensee `json:"licensee"`
+	Seats     uint     `json:"seats,omitempty"`
+	Relicense bool     `json:"relicense,omitempty"`
+	Offers    []string `json:"offers"`
+}
+
+// Order describes the state of an order.
+type Order struct {
+	OrderID  string `json:"orderID"`
+	Checkout string `json:"checkoutURL"`
+	// Status is pending until the order is paid for, then complete.
+	Status   string            `json:"status"`
+	Receipts []json.RawMessage `json:"receipts"`
+}
+
+// Order statuses.
+const (
+	orderPending   = "pending"
+	orderComplete  = "complete"
+	orderCancelled = "cancelled"
+)
+
+// CreateOrder places an order and returns it, with the URL of a page
+// where the licensee can pay.
+func CreateOrder(api string, request OrderRequest) (*Order, error) {
+	var order Order
+	err := postJSON(api+"/orders", "", request, &order)
+	if err != nil {
+		return nil, err
+	}
+	if !validUUID.MatchString(order.OrderID) {
+		return nil, errors.New("invalid orderID in response")
+	}
+	if order.Checkout == "" {
+		return nil, errors.New("no checkout URL in response")
+	}
+	return &order, nil
+}
+
+// GetOrder fetches the state of an order.
+func GetOrder(api string, orderID string) (*Order, error) {
+	var order Order
+	err := getJSON(api+"/orders/"+orderID, &order)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetReceipts asks an API for receipts, by licensee e-mail address
+// or order ID.
+func GetReceipts(api string, query url.Values) ([]json.RawMessage, error) {
+	var responseBody struct {
+		Receipts []json.RawMessage `json:"receipts"`
+	}
+	err := getJSON(api+"/receipts?"+query.Encode(), &responseBody)
+	if err != nil {
+		return nil, err
+	}
+	return responseBody.Receipts, nil
+}
+
+// getJSON fetches and decodes JSON data from a URL.
+func getJSON(url string, responseBody interface{}) error {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := apiClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return apiError(response.StatusCode, body)
+	}
+	return json.Unmarshal(body, responseBody)
+}
+
+// postJSON posts JSON data to a URL, with a bearer token if provided,
+// and decodes a JSON response.
+func postJSON(url string, token string, requestBody interface{}, responseBody interface{}) error {
+	data, err := json.Marshal(requestBody)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response, err := apiClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return apiError(response.StatusCode, body)
+	}
+	return json.Unmarshal(body, responseBody)
+}
+
+// apiStatusError is an error response from an API.
+type apiStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiStatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("API responded %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("API responded %d", e.StatusCode)
+}
+
+// transientAPIError reports whether a request that failed with err
+// might succeed if tried again: the API was overloaded, rate limited,
+// or unreachable.
+func transientAPIError(err error) bool {
+	var statusError *apiStatusError
+	if errors.As(err, &statusError) {
+		return statusError.StatusCode == http.StatusTooManyRequests || statusError.StatusCode >= 500
+	}
+	// *url.Error is itself a net.Error, even when the request was
+	// refused before it was made, so look at what it wraps.
+	if urlError, ok := err.(*url.Error); ok {
+		err = urlError.Err
+	}
+	var networkError net.Error
+	return errors.As(err, &networkError)
+}
+
+// apiError describes an error response, using the API's error message
+// if it provided one.
+func apiError(statusCode int, body []byte) error {
+	var message struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &message)
+	return &apiStatusError{StatusCode: statusCode, Message: message.Error}
+}
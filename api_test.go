@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/tls"
+	"golang.org/x/crypto/ed25519"
+	"io/ioutil"
+	"licensezero.com/cli2/devserver"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const devServerFixtures = "devserver/testdata"
+
+// withDevServer runs a script with a fake licensing API serving the
+// development fixtures.
+func withDevServer(t *testing.T, configure func(*devserver.Server), script func(server *httptest.Server)) {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newDevServer(devServerFixtures, privateKey)
+	if configure != nil {
+		configure(handler)
+	}
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+	withAPIClient(server.Client(), func() {
+		script(server)
+	})
+}
+
+func TestGetOffer(t *testing.T) {
+	withDevServer(t, nil, func(server *httptest.Server) {
+		offer, err := GetOffer(server.URL, "9aab7058-599a-43db-9449-5fc0971ecbfa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offer.LicensorID != "59e70a4d-ffee-4e9d-a526-7a9ff9161664" ||
+			offer.Pricing.Single != (Price{Amount: 1000, Currency: "USD"}) ||
+			offer.Pricing.Tiers[10] != (Price{Amount: 5000, Currency: "USD"}) {
+			t.Errorf("got %+v", offer)
+		}
+		_, err = GetOffer(server.URL, "00000000-0000-4000-8000-000000000000")
+		if err == nil {
+			t.Error("got missing offer")
+		}
+	})
+}
+
+func TestGetOfferServerError(t *testing.T) {
+	withDevServer(t, func(server *devserver.Server) {
+		server.ErrorRate = 1
+	}, func(server *httptest.Server) {
+		_, err := GetOffer(server.URL, "9aab7058-599a-43db-9449-5fc0971ecbfa")
+		if err == nil {
+			t.Error("no error from failing server")
+		}
+	})
+}
+
+func TestBuyFromDevServer(t *testing.T) {
+	withDevServer(t, func(server *devserver.Server) {
+		server.AutoComplete = true
+	}, func(server *httptest.Server) {
+		WithTestDir(t, func(directory string) {
+			allowTestAPI(t, directory, server.URL)
+			project := path.Join(directory, "project")
+			for _, offerID := range []string{
+				"9aab7058-599a-43db-9449-5fc0971ecbfa",
+				"d56ee0a6-4ed3-4793-9485-6135644c158f",
+			} {
+				err := writeTestArtifact(path.Join(project, "node_modules", offerID), server.URL, offerID)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			identity := testLicense().Values.Licensee
+			err := writeIdentity(directory, &identity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			env.CWD = project
+			if run([]string{"buy", "--yes", "--seats", "10"}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			for _, expected := range []string{"Total: $50.00", "Total: £80.00", "Imported 2 receipts."} {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("missing %q in output:\n%s", expected, stdout.String())
+				}
+			}
+			receipts, errors, err := ReadReceipts(directory)
+			if err != nil || len(errors) != 0 || len(receipts) != 2 {
+				t.Fatalf("read %d receipts, errors %v %v", len(receipts), errors, err)
+			}
+			for _, receipt := range receipts {
+				if receipt.ValidateSignature() != nil {
+					t.Error("invalid receipt signature")
+				}
+			}
+		})
+	})
+}
+
+func TestDevServerCertificate(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		_, privateKey, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certificate, certificatePEM, err := generateDevCertificate("localhost:8080")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundle := path.Join(directory, "dev-server.pem")
+		err = ioutil.WriteFile(bundle, certificatePEM, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewUnstartedServer(newDevServer(devServerFixtures, privateKey))
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+		server.StartTLS()
+		defer server.Close()
+		transport, err := newAPITransport(networkSettings{CABundle: bundle})
+		if err != nil {
+			t.Fatal(err)
+		}
+		withAPIClient(&http.Client{Transport: transport}, func() {
+			_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+			for _, host := range []string{"localhost", "127.0.0.1"} {
+				_, err := GetOffer("https://"+host+":"+port, "9aab7058-599a-43db-9449-5fc0971ecbfa")
+				if err != nil {
+					t.Errorf("%s: %v", host, err)
+				}
+			}
+		})
+	})
+}
+
+func TestGetOffersInvalid(t *testing.T) {
+	good := "9aab7058-599a-43db-9449-5fc0971ecbfa"
+	bad := "d56ee0a6-4ed3-4793-9485-6135644c158f"
+	missing := "00000000-0000-4000-8000-000000000000"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/offers/lookup" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"offers": {
+  "` + good + `": {
+    "url": "https://example.com",
+    "licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+    "pricing": {"single": {"currency": "USD", "amount": 1000}}
+  },
+  "` + bad + `": {"url": 5}
+}}`))
+	}))
+	defer server.Close()
+	withAPIClient(server.Client(), func() {
+		offers, errs := GetOffers(server.URL, []string{good, bad, missing})
+		if _, ok := offers[good]; !ok || len(offers) != 1 {
+			t.Errorf("offers %v", offers)
+		}
+		if _, ok := errs[bad].(*invalidOfferError); !ok {
+			t.Errorf("error for malformed offer: %v", errs[bad])
+		}
+		if errs[missing] == nil || errs[missing].Error() != "offer not found" {
+			t.Errorf("error for missing offer: %v", errs[missing])
+		}
+
+		WithTestDir(t, func(directory string) {
+			allowTestAPI(t, directory, server.URL)
+			project := path.Join(directory, "project")
+			err := writeTestArtifact(project, server.URL, bad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inventory, err := CompileInventory(directory, project, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(inventory.Diagnostics) != 1 || inventory.Diagnostics[0].Source != diagnosticParser {
+				t.Errorf("diagnostics: %v", inventory.Diagnostics)
+			}
+		})
+	})
+}
+
+func TestGetOffers(t *testing.T) {
+	offerIDs := []string{
+		"9aab7058-599a-43db-9449-5fc0971ecbfa",
+		"d56ee0a6-4ed3-4793-9485-6135644c158f",
+		"00000000-0000-4000-8000-000000000000",
+	}
+	for _, disableBatch := range []bool{false, true} {
+		_, privateKey, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler := newDevServer(devServerFixtures, privateKey)
+		handler.DisableBatch = disableBatch
+		var mutex sync.Mutex
+		requests := make(map[string]int)
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			if r.Method == "POST" {
+				requests["batch"]++
+			} else {
+				requests["single"]++
+			}
+			mutex.Unlock()
+			handler.ServeHTTP(w, r)
+		}))
+		withAPIClient(server.Client(), func() {
+			for round := 0; round < 2; round++ {
+				offers, errs := GetOffers(server.URL, offerIDs)
+				if len(offers) != 2 || len(errs) != 1 || errs[offerIDs[2]] == nil {
+					t.Errorf("batch disabled %v: got %d offers, errors %v", disableBatch, len(offers), errs)
+				}
+				if offers[offerIDs[1]].Pricing.Single != (Price{Amount: 800, Currency: "GBP"}) {
+					t.Errorf("batch disabled %v: got %+v", disableBatch, offers[offerIDs[1]])
+				}
+			}
+		})
+		server.Close()
+		if disableBatch && (requests["batch"] != 1 || requests["single"] != 6) {
+			t.Errorf("without batch lookup, made requests %v", requests)
+		}
+		if !disableBatch && (requests["batch"] != 2 || requests["single"] != 0) {
+			t.Errorf("with batch lookup, made requests %v", requests)
+		}
+	}
+}
@@ -1,9 +1,7 @@
 package main
 
 import (
-	"errors"
 	"github.com/mitchellh/mapstructure"
-	"github.com/xeipuuv/gojsonschema"
 )
 
 // Artifact encodes data about offers for an artifact.
@@ -19,13 +17,13 @@ type ArtifactOffer struct {
 }
 
 type artifact1_0_0Pre struct {
-	OfferArray []artifactOffer1_0_0Pre `mapstructure:"offers"`
+	OfferArray []artifactOffer1_0_0Pre `mapstructure:"offers" json:"offers"`
 }
 
 type artifactOffer1_0_0Pre struct {
-	API     string
-	OfferID string
-	Public  string
+	API     string `json:"api"`
+	OfferID string `json:"offerID"`
+	Public  string `json:"public,omitempty"`
 }
 
 func (a artifact1_0_0Pre) Offers() (offers []ArtifactOffer) {
@@ -42,6 +40,7 @@ func (a artifact1_0_0Pre) Offers() (offers []ArtifactOffer) {
 const artifact1_0_0PreSchema = `{
   "$schema": "http://json-schema.org/schema#",
   "$id": "https://schemas.licensezero.com/1.0.0-pre/artifact.json",
+  "title": "offers for an artifact",
   "type": "object",
   "required": [
     "offers"
@@ -88,26 +87,19 @@ const artifact1_0_0PreSchema = `{
 
 // ParseArtifact validates and parses parsed JSON data as a Artifact.
 func ParseArtifact(unstructured interface{}) (a Artifact, err error) {
-	if validV1Artifact(unstructured) {
-		return parseV1Artifact(unstructured), nil
+	version, data, err := detectVersion("artifact", unstructured)
+	if err != nil {
+		return a, err
+	}
+	switch version {
+	case "1.0.0-pre":
+		a = parseV1Artifact(data)
 	}
-	return a, errors.New("unknown schema")
+	return a, nil
 }
 
-var v1ArtifactSchema *gojsonschema.Schema = nil
-
 func validV1Artifact(parsed interface{}) bool {
-	if v1ArtifactSchema == nil {
-		v1ArtifactSchema, _ = schemaLoader().Compile(
-			gojsonschema.NewStringLoader(artifact1_0_0PreSchema),
-		)
-	}
-	dataLoader := gojsonschema.NewGoLoader(parsed)
-	result, err := v1ArtifactSchema.Validate(dataLoader)
-	if err != nil {
-		return false
-	}
-	return result.Valid()
+	return schemas.valid(schemaID("1.0.0-pre", "artifact"), parsed)
 }
 
 func parseV1Artifact(unstructured interface{}) (a artifact1_0_0Pre) {
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// artifactMetadata is editable metadata for an artifact, stored either
+// in licensezero.json or in the licensezero property of package.json.
+// Edits change only the parts of the file they need to, leaving key
+// order, indentation, and other properties as they were.
+type artifactMetadata struct {
+	Path     string
+	Property string
+	data     []byte
+	exists   bool
+}
+
+// openArtifactMetadata opens the metadata for the artifact in a
+// directory: licensezero.json, if it exists, or else package.json, if
+// it exists, or else a new licensezero.json.
+func openArtifactMetadata(directory string) (*artifactMetadata, error) {
+	licensezeroJSON := path.Join(directory, "licensezero.json")
+	data, err := ioutil.ReadFile(licensezeroJSON)
+	if err == nil {
+		return &artifactMetadata{Path: licensezeroJSON, data: data, exists: true}, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	packageJSON := path.Join(directory, "package.json")
+	data, err = ioutil.ReadFile(packageJSON)
+	if err == nil {
+		return &artifactMetadata{
+			Path:     packageJSON,
+			Property: "licensezero",
+			data:     data,
+			exists:   true,
+		}, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &artifactMetadata{
+		Path: licensezeroJSON,
+		data: []byte("{\n  \"offers\": []\n}\n"),
+	}, nil
+}
+
+// artifactObject locates the object holding artifact metadata,
+// adding it to package.json if necessary.
+func (m *artifactMetadata) artifactObject() (object jsonObject, err error) {
+	root, err := locateObject(m.data, 0)
+	if err != nil {
+		return object, fmt.Errorf("%s: %v", m.Path, err)
+	}
+	if m.Property == "" {
+		return root, nil
+	}
+	member := root.member(m.Property)
+	if member == nil {
+		m.data, err = setMember(m.data, &root, m.Property, artifact1_0_0Pre{
+			OfferArray: []artifactOffer1_0_0Pre{},
+		})
+		if err != nil {
+			return
+		}
+		return m.artifactObject()
+	}
+	object, err = locateObject(m.data, member.ValueStart)
+	if err != nil {
+		return object, fmt.Errorf("%s: %s: %v", m.Path, m.Property, err)
+	}
+	return
+}
+
+// offersArray locates the array of offers, adding it if necessary.
+func (m *artifactMetadata) offersArray() (array jsonArray, unit string, err error) {
+	object, err := m.artifactObject()
+	if err != nil {
+		return
+	}
+	unit = indentationUnit(m.data, &object)
+	member := object.member("offers")
+	if member == nil {
+		m.data, err = setMember(m.data, &object, "offers", []artifactOffer1_0_0Pre{})
+		if err != nil {
+			return
+		}
+		return m.offersArray()
+	}
+	array, err = locateArray(m.data, member.ValueStart)
+	if err != nil {
+		err = fmt.Errorf("%s: offers: %v", m.Path, err)
+	}
+	return
+}
+
+// Offers returns the offers in the metadata.
+func (m *artifactMetadata) Offers() (offers []ArtifactOffer, err error) {
+	array, _, err := m.offersArray()
+	if err != nil {
+		return
+	}
+	for _, element := range array.Elements {
+		var entry artifactOffer1_0_0Pre
+		err = json.Unmarshal(m.data[element.Start:element.End], &entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", m.Path, err)
+		}
+		offers = append(offers, ArtifactOffer{
+			API:     entry.API,
+			OfferID: entry.OfferID,
+			Public:  entry.Public,
+		})
+	}
+	return
+}
+
+func (m *artifactMetadata) indexOf(api string, offerID string) (int, error) {
+	offers, err := m.Offers()
+	if err != nil {
+		return -1, err
+	}
+	for index, offer := range offers {
+		if offer.API == api && offer.OfferID == offerID {
+			return index, nil
+		}
+	}
+	return -1, nil
+}
+
+// AddOffer adds an offer, refusing to add the same API and offerID
+// twice.
+func (m *artifactMetadata) AddOffer(offer ArtifactOffer) error {
+	index, err := m.indexOf(offer.API, offer.OfferID)
+	if err != nil {
+		return err
+	}
+	if index != -1 {
+		return errors.New("offer " + offer.OfferID + " from " + offer.API + " is already listed")
+	}
+	array, unit, err := m.offersArray()
+	if err != nil {
+		return err
+	}
+	m.data, err = appendElement(m.data, &array, unit, artifactOffer1_0_0Pre{
+		API:     offer.API,
+		OfferID: offer.OfferID,
+		Public:  offer.Public,
+	})
+	return err
+}
+
+// UpdateOffer replaces the offer with an API and offerID.
+func (m *artifactMetadata) UpdateOffer(api string, offerID string, offer ArtifactOffer) error {
+	index, err := m.indexOf(api, offerID)
+	if err != nil {
+		return err
+	}
+	if index == -1 {
+		return errors.New("offer " + offerID + " from " + api + " is not listed")
+	}
+	if offer.API != api || offer.OfferID != offerID {
+		duplicate, err := m.indexOf(offer.API, offer.OfferID)
+		if err != nil {
+			return err
+		}
+		if duplicate != -1 {
+			return errors.New("offer " + offer.OfferID + " from " + offer.API + " is already listed")
+		}
+	}
+	array, unit, err := m.offersArray()
+	if err != nil {
+		return err
+	}
+	m.data, err = replaceElement(m.data, &array, index, unit, artifactOffer1_0_0Pre{
+		API:     offer.API,
+		OfferID: offer.OfferID,
+		Public:  offer.Public,
+	})
+	return err
+}
+
+// RemoveOffer removes the offer with an API and offerID.
+func (m *artifactMetadata) RemoveOffer(api string, offerID string) error {
+	index, err := m.indexOf(api, offerID)
+	if err != nil {
+		return err
+	}
+	if index == -1 {
+		return errors.New("offer " + offerID + " from " + api + " is not listed")
+	}
+	array, _, err := m.offersArray()
+	if err != nil {
+		return err
+	}
+	m.data = removeElement(m.data, &array, index)
+	return nil
+}
+
+// Save validates the metadata against the artifact schema and writes
+// it atomically.
+func (m *artifactMetadata) Save() error {
+	var unstructured interface{}
+	err := json.Unmarshal(m.data, &unstructured)
+	if err != nil {
+		return fmt.Errorf("%s: %v", m.Path, err)
+	}
+	if m.Property != "" {
+		object, ok := unstructured.(map[string]interface{})
+		if !ok {
+			return errors.New(m.Path + " is not a JSON object")
+		}
+		unstructured = object[m.Property]
+	}
+	result, err := schemas.validate(schemaID("1.0.0-pre", "artifact"), unstructured)
+	if err != nil {
+		return err
+	}
+	if !result.Valid() {
+		return fmt.Errorf("invalid artifact metadata: %v", schemaErrors(result))
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(m.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	err = writeFileAtomically(m.Path, m.data, mode)
+	if err != nil {
+		return err
+	}
+	m.exists = true
+	return nil
+}
+
+var artifactSubcommands = map[string]subcommand{
+	"add": {
+		Summary: "Add an offer to licensezero.json or package.json.",
+		Handler: artifactAdd,
+	},
+	"list": {
+		Summary: "List offers in licensezero.json or package.json.",
+		Handler: artifactList,
+	},
+	"remove": {
+		Summary: "Remove an offer from licensezero.json or package.json.",
+		Handler: artifactRemove,
+	},
+}
+
+var artifactCommand = subcommand{
+	Summary: "Edit offers for the artifact in the current directory.",
+	Handler: func(args []string, env *environment) int {
+		return dispatch("artifact", artifactSubcommands, args, env)
+	},
+}
+
+const artifactAddUsage = `Add an offer to licensezero.json or package.json.
+
+Usage:
+  licensezero artifact add --offer ID [--api URL] [--public ID]
+`
+
+func artifactAdd(args []string, env *environment) int {
+	flagSet := flag.NewFlagSet("artifact add", flag.ContinueOnError)
+	flagSet.SetOutput(env.Stderr)
+	flagSet.Usage = func() { fmt.Fprint(env.Stderr, artifactAddUsage) }
+	api := flagSet.String("api", defaultAPI, "")
+	offerID := flagSet.String("offer", "", "")
+	public := flagSet.String("public", "", "")
+	if flagSet.Parse(args) != nil || flagSet.NArg() != 0 || *offerID == "" {
+		flagSet.Usage()
+		return 1
+	}
+	return editArtifactMetadata(env, func(metadata *artifactMetadata) error {
+		return metadata.AddOffer(ArtifactOffer{
+			API:     strings.TrimRight(*api, "/"),
+			OfferID: *offerID,
+			Public:  *public,
+		})
+	})
+}
+
+const artifactRemoveUsage = `Remove an offer from licensezero.json or package.json.
+
+Usage:
+  licensezero artifact remove --offer ID [--api URL]
+`
+
+func artifactRemove(args []string, env *environment) int {
+	flagSet := flag.NewFlagSet("artifact remove", flag.ContinueOnError)
+	flagSet.SetOutput(env.Stderr)
+	flagSet.Usage = func() { fmt.Fprint(env.Stderr, artifactRemoveUsage) }
+	api := flagSet.String("api", defaultAPI, "")
+	offerID := flagSet.String("offer", "", "")
+	if flagSet.Parse(args) != nil || flagSet.NArg() != 0 || *offerID == "" {
+		flagSet.Usage()
+		return 1
+	}
+	return editArtifactMetadata(env, func(metadata *artifactMetadata) error {
+		return metadata.RemoveOffer(strings.TrimRight(*api, "/"), *offerID)
+	})
+}
+
+func editArtifactMetadata(env *environment, edit func(*artifactMetadata) error) int {
+	metadata, err := openArtifactMetadata(env.CWD)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "Could not read artifact metadata:", err)
+		return 1
+	}
+	err = edit(metadata)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, err)
+		return 1
+	}
+	err = metadata.Save()
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "Could not write "+metadata.Path+":", err)
+		return 1
+	}
+	return 0
+}
+
+const artifactListUsage = `List offers in licensezero.json or package.json.
+
+Usage:
+  licensezero artifact list
+`
+
+func artifactList(args []string, env *environment) int {
+	flagSet := flag.NewFlagSet("artifact list", flag.ContinueOnError)
+	flagSet.SetOutput(env.Stderr)
+	flagSet.Usage = func() { fmt.Fprint(env.Stderr, artifactListUsage) }
+	if flagSet.Parse(args) != nil || flagSet.NArg() != 0 {
+		flagSet.Usage()
+		return 1
+	}
+	metadata, err := openArtifactMetadata(env.CWD)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "Could not read artifact metadata:", err)
+		return 1
+	}
+	if !metadata.exists {
+		fmt.Fprintln(env.Stderr, "No licensezero.json or package.json.")
+		return 1
+	}
+	offers, err := metadata.Offers()
+	if err != nil {
+		fmt.Fprintln(env.Stderr, err)
+		return 1
+	}
+	for _, offer := range offers {
+		if offer.Public == "" {
+			fmt.Fprintf(env.Stdout, "%s %s\n", offer.API, offer.OfferID)
+		} else {
+			fmt.Fprintf(env.Stdout, "%s %s %s\n", offer.API, offer.OfferID, offer.Public)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestArtifactAddAndRemove(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		filePath := path.Join(directory, "licensezero.json")
+		original := "{\n\t\"offers\": [\n\t\t{\n\t\t\t\"offerID\": \"36fce1e2-5e96-41fc-8776-4e632b546d96\",\n\t\t\t\"api\": \"https://api.licensezero.com\"\n\t\t}\n\t]\n}\n"
+		err := ioutil.WriteFile(filePath, []byte(original), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		code := run([]string{
+			"artifact", "add",
+			"--offer", "9aab7058-599a-43db-9449-5fc0971ecbfa",
+			"--public", "Parity-7.0.0",
+		}, env)
+		if code != 0 {
+			t.Fatal(stderr.String())
+		}
+		expected := "{\n\t\"offers\": [\n\t\t{\n\t\t\t\"offerID\": \"36fce1e2-5e96-41fc-8776-4e632b546d96\",\n\t\t\t\"api\": \"https://api.licensezero.com\"\n\t\t},\n\t\t{\n\t\t\t\"api\": \"https://api.licensezero.com\",\n\t\t\t\"offerID\": \"9aab7058-599a-43db-9449-5fc0971ecbfa\",\n\t\t\t\"public\": \"Parity-7.0.0\"\n\t\t}\n\t]\n}\n"
+		checkFile(t, filePath, expected)
+
+		env, _, stderr = newTestEnvironment(directory, "", nil)
+		if run([]string{"artifact", "add", "--offer", "9aab7058-599a-43db-9449-5fc0971ecbfa"}, env) == 0 {
+			t.Error("added duplicate offer")
+		}
+		if !strings.Contains(stderr.String(), "already listed") {
+			t.Error("did not report duplicate offer")
+		}
+		checkFile(t, filePath, expected)
+
+		env, stdout, _ := newTestEnvironment(directory, "", nil)
+		if run([]string{"artifact", "list"}, env) != 0 {
+			t.Fatal("could not list offers")
+		}
+		if stdout.String() != "https://api.licensezero.com 36fce1e2-5e96-41fc-8776-4e632b546d96\n"+
+			"https://api.licensezero.com 9aab7058-599a-43db-9449-5fc0971ecbfa Parity-7.0.0\n" {
+			t.Errorf("listed:\n%s", stdout.String())
+		}
+
+		env, _, stderr = newTestEnvironment(directory, "", nil)
+		if run([]string{"artifact", "remove", "--offer", "36fce1e2-5e96-41fc-8776-4e632b546d96"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		checkFile(t, filePath, "{\n\t\"offers\": [\n\t\t{\n\t\t\t\"api\": \"https://api.licensezero.com\",\n\t\t\t\"offerID\": \"9aab7058-599a-43db-9449-5fc0971ecbfa\",\n\t\t\t\"public\": \"Parity-7.0.0\"\n\t\t}\n\t]\n}\n")
+
+		env, _, stderr = newTestEnvironment(directory, "", nil)
+		if run([]string{"artifact", "remove", "--offer", "9aab7058-599a-43db-9449-5fc0971ecbfa"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		checkFile(t, filePath, "{\n\t\"offers\": []\n}\n")
+	})
+}
+
+func TestArtifactEditPackageJSON(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		filePath := path.Join(directory, "package.json")
+		original := `{
+  "name": "example",
+  "licensezero": {"offers": [{"api": "https://api.licensezero.com", "offerID": "36fce1e2-5e96-41fc-8776-4e632b546d96"}]},
+  "version": "1.0.0"
+}
+`
+		err := ioutil.WriteFile(filePath, []byte(original), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		metadata, err := openArtifactMetadata(directory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = metadata.AddOffer(ArtifactOffer{
+			API:     "https://api.licensezero.com",
+			OfferID: "9aab7058-599a-43db-9449-5fc0971ecbfa",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = metadata.UpdateOffer(
+			"https://api.licensezero.com",
+			"36fce1e2-5e96-41fc-8776-4e632b546d96",
+			ArtifactOffer{
+				API:     "https://api.licensezero.com",
+				OfferID: "36fce1e2-5e96-41fc-8776-4e632b546d96",
+				Public:  "Prosperity-3.0.0",
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = metadata.Save()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkFile(t, filePath, `{
+  "name": "example",
+  "licensezero": {"offers": [{"api":"https://api.licensezero.com","offerID":"36fce1e2-5e96-41fc-8776-4e632b546d96","public":"Prosperity-3.0.0"}, {"api":"https://api.licensezero.com","offerID":"9aab7058-599a-43db-9449-5fc0971ecbfa"}]},
+  "version": "1.0.0"
+}
+`)
+	})
+}
+
+func TestArtifactAddInvalid(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"artifact", "add", "--api", "http://insecure.example.com", "--offer", "9aab7058-599a-43db-9449-5fc0971ecbfa"}, env) == 0 {
+			t.Error("added offer that does not match schema")
+		}
+		if !strings.Contains(stderr.String(), "offers.0.api") {
+			t.Errorf("did not say which field is invalid:\n%s", stderr.String())
+		}
+		if _, err := ioutil.ReadFile(path.Join(directory, "licensezero.json")); err == nil {
+			t.Error("wrote invalid metadata")
+		}
+	})
+}
+
+func checkFile(t *testing.T, filePath string, expected string) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Errorf("%s contains:\n%s\nexpected:\n%s", filePath, data, expected)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const buyUsage = `Buy licenses for artifacts in the current directory.
+
+Usage:
+  licensezero buy [flags]
+
+Flags:
+  --seats N                 number of people who need licenses (default 1)
+  --relicense               buy relicensing instead of licenses
+  --identity EMAIL          identity to buy as, if you have several
+  --dry-run                 show what would be ordered, then stop
+  --yes                     order without asking for confirmation
+  --timeout DURATION        how long to wait for payment (default 1h)
+  --ignore-noncommercial    skip artifacts under noncommercial licenses
+  --ignore-reciprocal       skip artifacts under reciprocal licenses
+  --strict                  treat problems reading files or offers as errors
+
+Buy places an order with each licensing API, prints the checkout
+page for each order, and waits for payment. Once an order is paid,
+it imports the receipts.
+`
+
+// orderPollInterval is how long to wait between checks on an order.
+var orderPollInterval = 5 * time.Second
+
+var buyCommand = subcommand{
+	Summary: "Buy missing licenses.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("buy", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, buyUsage) }
+		seats := flagSet.Uint("seats", 1, "")
+		relicense := flagSet.Bool("relicense", false, "")
+		identityEMail := flagSet.String("identity", "", "")
+		dryRun := flagSet.Bool("dry-run", false, "")
+		yes := flagSet.Bool("yes", false, "")
+		timeout := flagSet.Duration("timeout", time.Hour, "")
+		ignoreNoncommercial := flagSet.Bool("ignore-noncommercial", false, "")
+		ignoreReciprocal := flagSet.Bool("ignore-reciprocal", false, "")
+		strict := flagSet.Bool("strict", false, "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 || *seats == 0 {
+			flagSet.Usage()
+			return 1
+		}
+		config, err := readConfigFile(env.Config)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read configuration:", err)
+			return 1
+		}
+		if err := env.setUpNetwork(); err != nil {
+			fmt.Fprintln(env.Stderr, "Could not configure network access:", err)
+			return 1
+		}
+		inventory, err := CompileInventory(env.Config, env.CWD, *ignoreNoncommercial, *ignoreReciprocal)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read dependencies:", err)
+			return 1
+		}
+		if reportDiagnostics(env.Stderr, inventory, *strict) {
+			return 1
+		}
+		err = printQuote(env.Stdout, inventory, *seats, *relicense, nil)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not quote:", err)
+			return 1
+		}
+		if len(inventory.Unlicensed) == 0 {
+			return 0
+		}
+		lines, _ := priceItems(inventory.Unlicensed, *seats, *relicense)
+		if len(lines) == 0 {
+			fmt.Fprintln(env.Stderr, "Nothing to order.")
+			return 1
+		}
+		if *dryRun {
+			return 0
+		}
+		identities, _, err := ReadIdentities(env.Config)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read identities:", err)
+			return 1
+		}
+		licensee, err := selectIdentity(identities, *identityEMail)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not choose identity:", err)
+			return 1
+		}
+		fmt.Fprintf(env.Stdout, "Licensee: %s <%s> (%s)\n", licensee.Name, licensee.EMail, licensee.Jurisdiction)
+		if !*yes {
+			fmt.Fprint(env.Stdout, "Place order? [y/N] ")
+			answer, err := env.readLine()
+			if err != nil || !isYes(answer) {
+				fmt.Fprintln(env.Stderr, "Cancelled.")
+				return 1
+			}
+		}
+
+		type placedOrder struct {
+			API    string
+			Order  *Order
+			Offers map[string]bool
+		}
+		// Once any order is placed, carry on past failures, so that
+		// orders already placed still get their receipts imported.
+		failed := false
+		var placed []placedOrder
+		for _, group := range groupLinesByAPI(lines) {
+			request := OrderRequest{
+				Licensee:  *licensee,
+				Seats:     *seats,
+				Relicense: *relicense,
+			}
+			offers := make(map[string]bool)
+			for _, line := range group.Lines {
+				request.Offers = append(request.Offers, line.Item.OfferID)
+				offers[line.Item.OfferID] = true
+			}
+			order, err := CreateOrder(group.API, request)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not place order with "+group.API+":", err)
+				failed = true
+				continue
+			}
+			fmt.Fprintf(env.Stdout, "Pay for order %s at:\n%s\n", order.OrderID, order.Checkout)
+			placed = append(placed, placedOrder{API: group.API, Order: order, Offers: offers})
+		}
+
+		imported := 0
+		for _, entry := range placed {
+			order, err := waitForOrder(entry.API, entry.Order.OrderID, *timeout)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not complete order "+entry.Order.OrderID+":", err)
+				failed = true
+				continue
+			}
+			for _, data := range order.Receipts {
+				receipt, err := parseReceiptData(data)
+				if err == nil {
+					err = checkOrderReceipt(receipt, entry.API, order.OrderID, entry.Offers, config)
+				}
+				if err != nil {
+					fmt.Fprintln(env.Stderr, "Invalid receipt for order "+order.OrderID+":", err)
+					failed = true
+					continue
+				}
+				saved, err := saveReceipt(env.Config, receipt, data)
+				if err != nil {
+					fmt.Fprintln(env.Stderr, "Could not save receipt:", err)
+					failed = true
+					continue
+				}
+				if saved {
+					imported++
+				}
+			}
+		}
+		fmt.Fprintf(env.Stdout, "Imported %d receipts.\n", imported)
+		if failed {
+			return 1
+		}
+		return 0
+	},
+}
+
+// selectIdentity chooses the identity to buy licenses as.
+func selectIdentity(identities []Licensee, email string) (*Licensee, error) {
+	if email != "" {
+		for index := range identities {
+			if strings.EqualFold(identities[index].EMail, email) {
+				return &identities[index], nil
+			}
+		}
+		return nil, errors.New("no identity for " + email)
+	}
+	switch len(identities) {
+	case 0:
+		return nil, errors.New("no identity; configure one with licensezero identify")
+	case 1:
+		return &identities[0], nil
+	default:
+		return nil, errors.New("several identities; choose one with --identity")
+	}
+}
+
+func isYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// apiLines are the line items to order from one API.
+type apiLines struct {
+	API   string
+	Lines []lineItem
+}
+
+// groupLinesByAPI groups line items by API, in order of first
+// appearance.
+func groupLinesByAPI(lines []lineItem) (groups []apiLines) {
+	indexes := make(map[string]int)
+	for _, line := range lines {
+		index, ok := indexes[line.Item.API]
+		if !ok {
+			index = len(groups)
+			indexes[line.Item.API] = index
+			groups = append(groups, apiLines{API: line.Item.API})
+		}
+		groups[index].Lines = append(groups[index].Lines, line)
+	}
+	return
+}
+
+// waitForOrder polls an order until it is complete, retrying after
+// transient errors until the timeout.
+func waitForOrder(api string, orderID string, timeout time.Duration) (*Order, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		order, err := GetOrder(api, orderID)
+		if err != nil {
+			if !transientAPIError(err) || time.Now().After(deadline) {
+				return nil, err
+			}
+			time.Sleep(orderPollInterval)
+			continue
+		}
+		switch order.Status {
+		case orderComplete:
+			return order, nil
+		case orderCancelled:
+			return nil, errors.New("order was cancelled")
+		case orderPending:
+		default:
+			return nil, errors.New("unknown order status: " + order.Status)
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for payment")
+		}
+		time.Sleep(orderPollInterval)
+	}
+}
+
+// checkOrderReceipt checks that a receipt belongs to an order and was
+// signed with the API's key.
+func checkOrderReceipt(receipt Receipt, api string, orderID string, offers map[string]bool, config *configFile) error {
+	if receipt.API() != api {
+		return errors.New("receipt from " + receipt.API() + " instead of " + api)
+	}
+	if receipt.OrderID() != orderID {
+		return errors.New("receipt for order " + receipt.OrderID())
+	}
+	if !offers[receipt.OfferID()] {
+		return errors.New("receipt for unordered offer " + receipt.OfferID())
+	}
+	return checkReceiptKey(receipt, api, config)
+}
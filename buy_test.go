@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"golang.org/x/crypto/ed25519"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBuy(t *testing.T) {
+	offerID := "9aab7058-599a-43db-9449-5fc0971ecbfa"
+	orderID := "2c743a84-09ce-4549-9f0d-19d8f53462bb"
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutex sync.Mutex
+	var ordered *OrderRequest
+	polls := 0
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch {
+		case r.URL.Path == "/offers/"+offerID:
+			w.Write([]byte(`{
+  "url": "https://example.com",
+  "licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+  "pricing": {"single": {"currency": "USD", "amount": 1000}}
+}`))
+		case r.Method == "POST" && r.URL.Path == "/orders":
+			var request OrderRequest
+			if json.NewDecoder(r.Body).Decode(&request) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ordered = &request
+			json.NewEncoder(w).Encode(Order{
+				OrderID:  orderID,
+				Checkout: "https://example.com/checkout/" + orderID,
+				Status:   orderPending,
+			})
+		case r.URL.Path == "/orders/"+orderID:
+			polls++
+			if polls < 2 {
+				json.NewEncoder(w).Encode(Order{OrderID: orderID, Status: orderPending})
+				return
+			}
+			license := testLicense()
+			license.Values.API = server.URL
+			license.Values.OfferID = offerID
+			license.Values.OrderID = orderID
+			license.Values.Licensee = ordered.Licensee
+			receipt, err := signV1Receipt(license, privateKey)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			data, _ := json.Marshal(receipt)
+			json.NewEncoder(w).Encode(Order{
+				OrderID:  orderID,
+				Status:   orderComplete,
+				Receipts: []json.RawMessage{data},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	interval := orderPollInterval
+	orderPollInterval = time.Millisecond
+	defer func() { orderPollInterval = interval }()
+
+	withAPIClient(server.Client(), func() {
+		WithTestDir(t, func(directory string) {
+			allowTestAPI(t, directory, server.URL)
+			project := path.Join(directory, "project")
+			err := writeTestArtifact(path.Join(project, "node_modules", "dependency"), server.URL, offerID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity := testLicense().Values.Licensee
+			err = writeIdentity(directory, &identity)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			env.CWD = project
+			if run([]string{"buy", "--dry-run"}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "Total: $10.00") || ordered != nil {
+				t.Errorf("dry run:\n%s", stdout.String())
+			}
+
+			env, _, _ = newTestEnvironment(directory, "n\n", nil)
+			env.CWD = project
+			if run([]string{"buy"}, env) == 0 || ordered != nil {
+				t.Error("ordered without confirmation")
+			}
+
+			// Receipts must be signed with the key configured for the API.
+			err = ioutil.WriteFile(configFilePath(directory), []byte(`{
+  "apis": ["`+server.URL+`"],
+  "publicKeys": {"`+server.URL+`": "`+strings.Repeat("0", 64)+`"}
+}`), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, _, stderr = newTestEnvironment(directory, "y\n", nil)
+			env.CWD = project
+			if run([]string{"buy"}, env) == 0 {
+				t.Error("saved a receipt not signed with the API's key")
+			}
+			if !strings.Contains(stderr.String(), "not signed with the API's key") {
+				t.Errorf("stderr:\n%s", stderr.String())
+			}
+			if receipts, _, _ := ReadReceipts(directory); len(receipts) != 0 {
+				t.Errorf("saved %d receipts", len(receipts))
+			}
+			allowTestAPI(t, directory, server.URL)
+
+			env, stdout, stderr = newTestEnvironment(directory, "y\n", nil)
+			env.CWD = project
+			if run([]string{"buy"}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			if ordered == nil || len(ordered.Offers) != 1 || ordered.Offers[0] != offerID ||
+				ordered.Licensee != identity {
+				t.Errorf("ordered %+v", ordered)
+			}
+			for _, expected := range []string{
+				"https://example.com/checkout/" + orderID,
+				"Imported 1 receipts.",
+			} {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("missing %q in output:\n%s", expected, stdout.String())
+				}
+			}
+
+			env, stdout, _ = newTestEnvironment(directory, "", nil)
+			env.CWD = project
+			if run([]string{"buy", "--yes"}, env) != 0 {
+				t.Fatal("could not run buy again")
+			}
+			if !strings.Contains(stdout.String(), "No licenses to buy.") {
+				t.Errorf("bought again:\n%s", stdout.String())
+			}
+		})
+	})
+}
+
+func TestSelectIdentity(t *testing.T) {
+	first := Licensee{Name: "First", EMail: "first@example.com", Jurisdiction: "US-CA"}
+	second := Licensee{Name: "Second", EMail: "second@example.com", Jurisdiction: "US-CA"}
+	if _, err := selectIdentity(nil, ""); err == nil {
+		t.Error("selected without identities")
+	}
+	if selected, err := selectIdentity([]Licensee{first}, ""); err != nil || *selected != first {
+		t.Error("did not select only identity")
+	}
+	if _, err := selectIdentity([]Licensee{first, second}, ""); err == nil {
+		t.Error("selected among several identities")
+	}
+	if selected, err := selectIdentity([]Licensee{first, second}, "Second@example.com"); err != nil || *selected != second {
+		t.Error("did not select identity by e-mail")
+	}
+}
+
+func TestBuyContinuesAfterFailures(t *testing.T) {
+	goodOffer := "9aab7058-599a-43db-9449-5fc0971ecbfa"
+	badOffer := "d56ee0a6-4ed3-4793-9485-6135644c158f"
+	orderID := "2c743a84-09ce-4549-9f0d-19d8f53462bb"
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer := []byte(`{
+  "url": "https://example.com",
+  "licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+  "pricing": {"single": {"currency": "USD", "amount": 1000}}
+}`)
+	var mutex sync.Mutex
+	polls := 0
+	var good *httptest.Server
+	good = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch {
+		case r.URL.Path == "/offers/"+goodOffer:
+			w.Write(offer)
+		case r.Method == "POST" && r.URL.Path == "/orders":
+			json.NewEncoder(w).Encode(Order{
+				OrderID:  orderID,
+				Checkout: "https://example.com/checkout/" + orderID,
+				Status:   orderPending,
+			})
+		case r.URL.Path == "/orders/"+orderID:
+			// Fail transiently before the order completes.
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if polls == 2 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			license := testLicense()
+			license.Values.API = good.URL
+			license.Values.OfferID = goodOffer
+			license.Values.OrderID = orderID
+			receipt, err := signV1Receipt(license, privateKey)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			data, _ := json.Marshal(receipt)
+			json.NewEncoder(w).Encode(Order{
+				OrderID:  orderID,
+				Status:   orderComplete,
+				Receipts: []json.RawMessage{data},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer good.Close()
+	bad := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/offers/"+badOffer:
+			w.Write(offer)
+		case r.Method == "POST" && r.URL.Path == "/orders":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer bad.Close()
+	interval := orderPollInterval
+	orderPollInterval = time.Millisecond
+	defer func() { orderPollInterval = interval }()
+
+	// Both test servers trust the same certificate.
+	withAPIClient(good.Client(), func() {
+		WithTestDir(t, func(directory string) {
+			err := ioutil.WriteFile(configFilePath(directory), []byte(`{"apis": ["`+good.URL+`", "`+bad.URL+`"]}`), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			project := path.Join(directory, "project")
+			for directory, artifact := range map[string][2]string{
+				"good": {good.URL, goodOffer},
+				"bad":  {bad.URL, badOffer},
+			} {
+				err := writeTestArtifact(path.Join(project, "node_modules", directory), artifact[0], artifact[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			identity := testLicense().Values.Licensee
+			if err := writeIdentity(directory, &identity); err != nil {
+				t.Fatal(err)
+			}
+
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			env.CWD = project
+			if run([]string{"buy", "--yes"}, env) == 0 {
+				t.Error("reported success after an order failed")
+			}
+			if !strings.Contains(stderr.String(), "Could not place order with "+bad.URL) {
+				t.Errorf("stderr:\n%s", stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "Imported 1 receipts.") {
+				t.Errorf("did not import the placed order:\n%s\n%s", stdout.String(), stderr.String())
+			}
+			if polls != 3 {
+				t.Errorf("polled %d times", polls)
+			}
+		})
+	})
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// canonicalJSON serializes a value as canonical JSON: object keys
+// sorted, no insignificant whitespace, and strings and numbers
+// written according to RFC 8785, the JSON Canonicalization Scheme.
+//
+// Signed data, like receipt licenses, must be serialized the same
+// way by signers and verifiers, no matter which schema version or
+// which optional properties they contain.
+func canonicalJSON(value interface{}) ([]byte, error) {
+	generic, err := toGenericJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	err = writeCanonicalJSON(&buffer, generic)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// toGenericJSON round-trips a value through encoding/json, so that
+// structs, struct tags, and omitempty apply, and the result contains
+// only maps, slices, strings, numbers, booleans, and nil.
+func toGenericJSON(value interface{}) (generic interface{}, err error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&generic)
+	return
+}
+
+func writeCanonicalJSON(buffer *bytes.Buffer, value interface{}) error {
+	switch typed := value.(type) {
+	case nil:
+		buffer.WriteString("null")
+	case bool:
+		if typed {
+			buffer.WriteString("true")
+		} else {
+			buffer.WriteString("false")
+		}
+	case string:
+		writeCanonicalString(buffer, typed)
+	case json.Number:
+		number, err := typed.Float64()
+		if err != nil {
+			return err
+		}
+		formatted, err := formatCanonicalNumber(number)
+		if err != nil {
+			return err
+		}
+		buffer.WriteString(formatted)
+	case float64:
+		formatted, err := formatCanonicalNumber(typed)
+		if err != nil {
+			return err
+		}
+		buffer.WriteString(formatted)
+	case []interface{}:
+		buffer.WriteByte('[')
+		for index, element := range typed {
+			if index > 0 {
+				buffer.WriteByte(',')
+			}
+			err := writeCanonicalJSON(buffer, element)
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+		buffer.WriteByte('{')
+		for index, key := range keys {
+			if index > 0 {
+				buffer.WriteByte(',')
+			}
+			writeCanonicalString(buffer, key)
+			buffer.WriteByte(':')
+			err := writeCanonicalJSON(buffer, typed[key])
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	default:
+		return errors.New("cannot canonicalize value")
+	}
+	return nil
+}
+
+// RFC 8785 sorts property names by their UTF-16 code units, which
+// differs from sorting by UTF-8 bytes outside the Basic Multilingual
+// Plane.
+func lessUTF16(a, b string) bool {
+	aUnits := utf16.Encode([]rune(a))
+	bUnits := utf16.Encode([]rune(b))
+	for i := 0; i < len(aUnits) && i < len(bUnits); i++ {
+		if aUnits[i] != bUnits[i] {
+			return aUnits[i] < bUnits[i]
+		}
+	}
+	return len(aUnits) < len(bUnits)
+}
+
+const lowerHex = "0123456789abcdef"
+
+// Unlike encoding/json, escape only what JSON requires, and leave
+// HTML characters and non-ASCII text as they are.
+func writeCanonicalString(buffer *bytes.Buffer, input string) {
+	buffer.WriteByte('"')
+	for _, r := range input {
+		switch r {
+		case '"':
+			buffer.WriteString(`\"`)
+		case '\\':
+			buffer.WriteString(`\\`)
+		case '\b':
+			buffer.WriteString(`\b`)
+		case '\f':
+			buffer.WriteString(`\f`)
+		case '\n':
+			buffer.WriteString(`\n`)
+		case '\r':
+			buffer.WriteString(`\r`)
+		case '\t':
+			buffer.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				buffer.WriteString(`\u00`)
+				buffer.WriteByte(lowerHex[r>>4])
+				buffer.WriteByte(lowerHex[r&0xF])
+			} else {
+				buffer.WriteRune(r)
+			}
+		}
+	}
+	buffer.WriteByte('"')
+}
+
+// Format numbers the way ECMAScript's Number.prototype.toString does,
+// as RFC 8785 requires.
+func formatCanonicalNumber(number float64) (string, error) {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return "", errors.New("cannot canonicalize NaN or infinity")
+	}
+	if number == 0 {
+		return "0", nil
+	}
+	format := byte('f')
+	if abs := math.Abs(number); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+	formatted := strconv.FormatFloat(number, format, -1, 64)
+	if format == 'e' {
+		// Shorten exponents like e-07 to e-7.
+		length := len(formatted)
+		if length >= 4 &&
+			formatted[length-4] == 'e' &&
+			formatted[length-2] == '0' {
+			formatted = formatted[:length-2] + formatted[length-1:]
+		}
+	}
+	return formatted, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"golang.org/x/crypto/ed25519"
+	"testing"
+)
+
+func TestCanonicalJSONSortsKeys(t *testing.T) {
+	// Property sorting example from RFC 8785, section 3.2.3.
+	input := []byte(`{
+  "€": "Euro Sign",
+  "\r": "Carriage Return",
+  "דּ": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "😀": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "ö": "Latin Small Letter O With Diaeresis"
+}`)
+	expected := "{" +
+		`"\r":"Carriage Return",` +
+		`"1":"One",` +
+		"\"\u0080\":\"Control\"," +
+		"\"ö\":\"Latin Small Letter O With Diaeresis\"," +
+		"\"€\":\"Euro Sign\"," +
+		"\"\U0001F600\":\"Emoji: Grinning Face\"," +
+		"\"דּ\":\"Hebrew Letter Dalet With Dagesh\"" +
+		"}"
+	checkCanonicalJSON(t, input, expected)
+}
+
+func TestCanonicalJSONStrings(t *testing.T) {
+	input := []byte(`{"name":"Zoë Ångström <zoe@example.com>","control":"\u0001\n\u001f","quote":"\"\\/"}`)
+	expected := `{"control":"\u0001\n\u001f","name":"Zoë Ångström <zoe@example.com>","quote":"\"\\/"}`
+	checkCanonicalJSON(t, input, expected)
+}
+
+func TestCanonicalJSONNumbers(t *testing.T) {
+	vectors := map[string]string{
+		`[1000]`:                  `[1000]`,
+		`[-0]`:                    `[0]`,
+		`[1.0]`:                   `[1]`,
+		`[0.000001]`:              `[0.000001]`,
+		`[1e-7]`:                  `[1e-7]`,
+		`[1e21]`:                  `[1e+21]`,
+		`[1e20]`:                  `[100000000000000000000]`,
+		`[333333333.33333329]`:    `[333333333.3333333]`,
+		`[4.50]`:                  `[4.5]`,
+		`[9007199254740993]`:      `[9007199254740992]`,
+		`[true,false,null,"x"]`:   `[true,false,null,"x"]`,
+		`[ { "b" : 2, "a" : 1 }]`: `[{"a":1,"b":2}]`,
+	}
+	for input, expected := range vectors {
+		checkCanonicalJSON(t, []byte(input), expected)
+	}
+}
+
+func checkCanonicalJSON(t *testing.T, input []byte, expected string) {
+	var unstructured interface{}
+	err := json.Unmarshal(input, &unstructured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := canonicalJSON(unstructured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != expected {
+		t.Errorf("canonicalized %s as %s, expected %s", input, output, expected)
+	}
+}
+
+func TestNonASCIIReceiptSignature(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	message := `{"form":"Test license form.","values":{"api":"https://api.licensezero.com","effective":"2018-11-13T20:20:39Z","licensee":{"email":"jürgen@example.de","jurisdiction":"DE-BE","name":"Jürgen Groß"},"licensor":{"email":"licensor@example.com","jurisdiction":"JP-13","licensorID":"59e70a4d-ffee-4e9d-a526-7a9ff9161664","name":"山田 太郎"},"offerID":"9aab7058-599a-43db-9449-5fc0971ecbfa","orderID":"2c743a84-09ce-4549-9f0d-19d8f53462bb","price":{"amount":1000,"currency":"EUR"}}}`
+	signature := ed25519.Sign(privateKey, []byte(message))
+	combined := "{" +
+		quote("key") + ":" + quote(hex.EncodeToString(publicKey)) + "," +
+		quote("signature") + ":" + quote(hex.EncodeToString(signature)) + "," +
+		quote("license") + ":" + message +
+		"}"
+	var unstructured interface{}
+	err := json.Unmarshal([]byte(combined), &unstructured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := parseV1Receipt(unstructured)
+	serialized, err := canonicalJSON(receipt.License)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(serialized) != message {
+		t.Errorf("serialized license as %s", serialized)
+	}
+	if err := receipt.ValidateSignature(); err != nil {
+		t.Error("invalidates valid signature")
+	}
+	receipt.License.Values.Licensee.Name = "Jurgen Gross"
+	if err := receipt.ValidateSignature(); err == nil {
+		t.Error("validates signature for changed license")
+	}
+}
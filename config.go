@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// configFile holds settings from config.json in the configuration
+// directory.
+type configFile struct {
+	// APIs lists the origins of licensing APIs that artifacts may
+	// name, like https://api.licensezero.com.
+	APIs []string `json:"apis,omitempty"`
+	// PublicKeys maps API origins to the hex-encoded ed25519 public
+	// keys they sign offers with.
+	PublicKeys map[string]string `json:"publicKeys,omitempty"`
+	// RequireSignedOffers refuses offers without valid signatures,
+	// instead of warning about them.
+	RequireSignedOffers bool `json:"requireSignedOffers,omitempty"`
+	// Proxy, NoProxy, CABundle, ClientCertificate, and ClientKey
+	// configure network access. See networkSettings.
+	Proxy             string `json:"proxy,omitempty"`
+	NoProxy           string `json:"noProxy,omitempty"`
+	CABundle          string `json:"caBundle,omitempty"`
+	ClientCertificate string `json:"clientCertificate,omitempty"`
+	ClientKey         string `json:"clientKey,omitempty"`
+}
+
+func configFilePath(configPath string) string {
+	return path.Join(configPath, "config.json")
+}
+
+// readConfigFile reads config.json, returning default settings if it
+// doesn't exist. Without an "apis" setting, only the default API is
+// allowed.
+func readConfigFile(configPath string) (*configFile, error) {
+	filePath := configFilePath(configPath)
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &configFile{APIs: []string{defaultAPI}}, nil
+		}
+		return nil, err
+	}
+	var config configFile
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, errors.New(filePath + ": " + err.Error())
+	}
+	if config.APIs == nil {
+		config.APIs = []string{defaultAPI}
+	}
+	for index, api := range config.APIs {
+		if _, err := apiOrigin(api); err != nil {
+			return nil, errors.New(filePath + ": " + err.Error())
+		}
+		// Match API URLs as given on the command line and in receipts.
+		config.APIs[index] = strings.TrimRight(api, "/")
+	}
+	for api, publicKey := range config.PublicKeys {
+		if _, err := apiOrigin(api); err != nil {
+			return nil, errors.New(filePath + ": " + err.Error())
+		}
+		if !validPublicKey.MatchString(publicKey) {
+			return nil, errors.New(filePath + ": invalid public key for " + api)
+		}
+	}
+	return &config, nil
+}
+
+// apiOrigin returns the origin of an HTTPS API URL, like
+// https://api.licensezero.com.
+func apiOrigin(api string) (string, error) {
+	parsed, err := url.Parse(api)
+	if err != nil {
+		return "", errors.New("invalid API URL: " + api)
+	}
+	if parsed.Scheme != "https" {
+		return "", errors.New("API is not HTTPS: " + api)
+	}
+	if parsed.Host == "" || parsed.User != nil {
+		return "", errors.New("invalid API URL: " + api)
+	}
+	return "https://" + strings.ToLower(parsed.Host), nil
+}
+
+// checkAPIAllowed returns an error describing why the CLI may not
+// contact an API, or nil if it may.
+func (config *configFile) checkAPIAllowed(api string) error {
+	origin, err := apiOrigin(api)
+	if err != nil {
+		return err
+	}
+	for _, allowed := range config.APIs {
+		allowedOrigin, err := apiOrigin(allowed)
+		if err == nil && allowedOrigin == origin {
+			return nil
+		}
+	}
+	return errors.New("API not in allowed list: " + origin)
+}
+
+var validPublicKey = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// publicKeyFor returns the public key configured for an API, if any.
+func (config *configFile) publicKeyFor(api string) (string, bool) {
+	origin, err := apiOrigin(api)
+	if err != nil {
+		return "", false
+	}
+	for configured, publicKey := range config.PublicKeys {
+		configuredOrigin, err := apiOrigin(configured)
+		if err == nil && configuredOrigin == origin {
+			return publicKey, true
+		}
+	}
+	return "", false
+}
+
+// verifyOffer checks that an offer fetched from an API as offerID was
+// signed as that offer with the public key configured for the API.
+func (config *configFile) verifyOffer(api string, offerID string, offer *Offer) error {
+	origin, err := apiOrigin(api)
+	if err != nil {
+		return err
+	}
+	if publicKey, ok := config.publicKeyFor(api); ok {
+		return offer.Verify(publicKey, api, offerID)
+	}
+	if offer.Signature == "" {
+		return errUnsignedOffer
+	}
+	return errors.New("no public key configured for " + origin)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/hex"
+	"golang.org/x/crypto/ed25519"
+	"io/ioutil"
+	"licensezero.com/cli2/devserver"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+)
+
+// allowTestAPI writes a config.json allowing an API.
+func allowTestAPI(t *testing.T, directory string, api string) {
+	err := ioutil.WriteFile(configFilePath(directory), []byte(`{"apis": ["`+api+`"]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckAPIAllowed(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		config, err := readConfigFile(directory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := config.checkAPIAllowed("https://api.licensezero.com"); err != nil {
+			t.Error("refused default API:", err)
+		}
+		allowTestAPI(t, directory, "https://API.example.com:8443")
+		config, err = readConfigFile(directory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := config.checkAPIAllowed("https://api.example.com:8443/v1"); err != nil {
+			t.Error("refused allowed API:", err)
+		}
+		for _, refused := range []string{
+			"https://api.licensezero.com",
+			"http://api.example.com:8443",
+			"https://api.example.com",
+			"https://user@api.example.com:8443",
+			"https://internal.example.com:8443",
+		} {
+			if config.checkAPIAllowed(refused) == nil {
+				t.Errorf("allowed %s", refused)
+			}
+		}
+	})
+}
+
+func TestInventoryDisallowed(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		project := path.Join(directory, "project")
+		offerID := "9aab7058-599a-43db-9449-5fc0971ecbfa"
+		err := writeTestArtifact(path.Join(project, "node_modules", "dependency"), "https://internal.example.com", offerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inventory, err := CompileInventory(directory, project, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inventory.Disallowed) != 1 || len(inventory.Invalid) != 0 {
+			t.Fatalf("disallowed %d, invalid %d", len(inventory.Disallowed), len(inventory.Invalid))
+		}
+		if reason := inventory.Disallowed[0].Reason.Error(); !strings.Contains(reason, "not in allowed list") {
+			t.Errorf("refused because %s", reason)
+		}
+	})
+}
+
+func TestRefuseOffOriginRedirect(t *testing.T) {
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("followed redirect to another origin")
+	}))
+	defer other.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+	withAPIClient(server.Client(), func() {
+		_, err := GetOffer(server.URL, "9aab7058-599a-43db-9449-5fc0971ecbfa")
+		if err == nil || !strings.Contains(err.Error(), "another origin") {
+			t.Error("did not refuse redirect:", err)
+		}
+		_, err = GetOffer(strings.Replace(server.URL, "https:", "http:", 1), "9aab7058-599a-43db-9449-5fc0971ecbfa")
+		if err == nil || !strings.Contains(err.Error(), "HTTPS") {
+			t.Error("did not refuse plain HTTP:", err)
+		}
+	})
+}
+
+func TestVerifyOffers(t *testing.T) {
+	var publicKey string
+	withDevServer(t, func(server *devserver.Server) {
+		publicKey = server.PublicKey
+	}, func(server *httptest.Server) {
+		WithTestDir(t, func(directory string) {
+			project := path.Join(directory, "project")
+			err := writeTestArtifact(path.Join(project, "node_modules", "dependency"), server.URL, "9aab7058-599a-43db-9449-5fc0971ecbfa")
+			if err != nil {
+				t.Fatal(err)
+			}
+			otherKey := strings.Repeat("ab", 32)
+			for _, vector := range []struct {
+				config     string
+				unverified string
+				disallowed bool
+			}{
+				{`{"apis": ["` + server.URL + `"], "publicKeys": {"` + server.URL + `": "` + publicKey + `"}}`, "", false},
+				{`{"apis": ["` + server.URL + `"], "publicKeys": {"` + server.URL + `": "` + otherKey + `"}}`, "invalid offer signature", false},
+				{`{"apis": ["` + server.URL + `"]}`, "no public key", false},
+				{`{"apis": ["` + server.URL + `"], "requireSignedOffers": true}`, "no public key", true},
+			} {
+				err := ioutil.WriteFile(configFilePath(directory), []byte(vector.config), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				inventory, err := CompileInventory(directory, project, false, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var item Item
+				if vector.disallowed {
+					if len(inventory.Disallowed) != 1 {
+						t.Errorf("%s: did not refuse offer", vector.config)
+						continue
+					}
+					item = inventory.Disallowed[0].Item
+				} else {
+					if len(inventory.Licensable) != 1 {
+						t.Errorf("%s: refused offer", vector.config)
+						continue
+					}
+					item = inventory.Licensable[0]
+				}
+				if vector.unverified == "" && item.Unverified != nil {
+					t.Errorf("%s: unverified: %v", vector.config, item.Unverified)
+				} else if vector.unverified != "" && (item.Unverified == nil || !strings.Contains(item.Unverified.Error(), vector.unverified)) {
+					t.Errorf("%s: expected %q, got %v", vector.config, vector.unverified, item.Unverified)
+				}
+			}
+		})
+	})
+}
+
+func TestVerifySwappedOffer(t *testing.T) {
+	requested := "9aab7058-599a-43db-9449-5fc0971ecbfa"
+	other := "d56ee0a6-4ed3-4793-9485-6135644c158f"
+	otherData, err := ioutil.ReadFile(path.Join(devServerFixtures, "offers", other+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	for _, batch := range []bool{false, true} {
+		var server *httptest.Server
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Answer for the requested offer with a genuine signature
+			// of the other offer.
+			swapped, err := signOffer(server.URL, other, otherData, privateKey)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			switch {
+			case batch && r.URL.Path == "/offers/lookup":
+				w.Write([]byte(`{"offers": {"` + requested + `": ` + string(swapped) + `}}`))
+			case !batch && r.URL.Path == "/offers/"+requested:
+				w.Write(swapped)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		withAPIClient(server.Client(), func() {
+			WithTestDir(t, func(directory string) {
+				project := path.Join(directory, "project")
+				err := writeTestArtifact(project, server.URL, requested)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = ioutil.WriteFile(configFilePath(directory), []byte(`{
+  "apis": ["`+server.URL+`"],
+  "publicKeys": {"`+server.URL+`": "`+publicKey+`"},
+  "requireSignedOffers": true
+}`), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				inventory, err := CompileInventory(directory, project, false, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(inventory.Licensable) != 0 || len(inventory.Disallowed) != 1 {
+					t.Fatalf("batch %v: accepted swapped offer", batch)
+				}
+				if reason := inventory.Disallowed[0].Reason.Error(); !strings.Contains(reason, "invalid offer signature") {
+					t.Errorf("batch %v: refused because %s", batch, reason)
+				}
+			})
+		})
+		server.Close()
+	}
+}
+
+func TestVerifyUnsignedOffer(t *testing.T) {
+	offer := Offer{}
+	if offer.Verify(strings.Repeat("ab", 32), defaultAPI, "9aab7058-599a-43db-9449-5fc0971ecbfa") != errUnsignedOffer {
+		t.Error("verified unsigned offer")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Currency describes an ISO 4217 currency.
+type Currency struct {
+	Code string
+	Name string
+	// MinorUnits is the number of decimal places between major and
+	// minor units, like 2 for dollars and cents.
+	MinorUnits uint
+	// Symbol is the currency's symbol, if it has a distinctive one.
+	Symbol string
+}
+
+// currencies lists active ISO 4217 currencies, excluding funds,
+// precious metals, and testing codes.
+var currencies = []Currency{
+	{Code: "AED", Name: "UAE Dirham", MinorUnits: 2, Symbol: ""},
+	{Code: "AFN", Name: "Afghani", MinorUnits: 2, Symbol: ""},
+	{Code: "ALL", Name: "Lek", MinorUnits: 2, Symbol: ""},
+	{Code: "AMD", Name: "Armenian Dram", MinorUnits: 2, Symbol: ""},
+	{Code: "ANG", Name: "Netherlands Antillean Guilder", MinorUnits: 2, Symbol: ""},
+	{Code: "AOA", Name: "Kwanza", MinorUnits: 2, Symbol: ""},
+	{Code: "ARS", Name: "Argentine Peso", MinorUnits: 2, Symbol: ""},
+	{Code: "AUD", Name: "Australian Dollar", MinorUnits: 2, Symbol: "A$"},
+	{Code: "AWG", Name: "Aruban Florin", MinorUnits: 2, Symbol: ""},
+	{Code: "AZN", Name: "Azerbaijan Manat", MinorUnits: 2, Symbol: ""},
+	{Code: "BAM", Name: "Convertible Mark", MinorUnits: 2, Symbol: ""},
+	{Code: "BBD", Name: "Barbados Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "BDT", Name: "Taka", MinorUnits: 2, Symbol: ""},
+	{Code: "BGN", Name: "Bulgarian Lev", MinorUnits: 2, Symbol: ""},
+	{Code: "BHD", Name: "Bahraini Dinar", MinorUnits: 3, Symbol: ""},
+	{Code: "BIF", Name: "Burundi Franc", MinorUnits: 0, Symbol: ""},
+	{Code: "BMD", Name: "Bermudian Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "BND", Name: "Brunei Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "BOB", Name: "Boliviano", MinorUnits: 2, Symbol: ""},
+	{Code: "BRL", Name: "Brazilian Real", MinorUnits: 2, Symbol: "R$"},
+	{Code: "BSD", Name: "Bahamian Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "BTN", Name: "Ngultrum", MinorUnits: 2, Symbol: ""},
+	{Code: "BWP", Name: "Pula", MinorUnits: 2, Symbol: ""},
+	{Code: "BYN", Name: "Belarusian Ruble", MinorUnits: 2, Symbol: ""},
+	{Code: "BZD", Name: "Belize Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "CAD", Name: "Canadian Dollar", MinorUnits: 2, Symbol: "CA$"},
+	{Code: "CDF", Name: "Congolese Franc", MinorUnits: 2, Symbol: ""},
+	{Code: "CHF", Name: "Swiss Franc", MinorUnits: 2, Symbol: ""},
+	{Code: "CLP", Name: "Chilean Peso", MinorUnits: 0, Symbol: ""},
+	{Code: "CNY", Name: "Yuan Renminbi", MinorUnits: 2, Symbol: "CN¥"},
+	{Code: "COP", Name: "Colombian Peso", MinorUnits: 2, Symbol: ""},
+	{Code: "CRC", Name: "Costa Rican Colon", MinorUnits: 2, Symbol: ""},
+	{Code: "CUP", Name: "Cuban Peso", MinorUnits: 2, Symbol: ""},
+	{Code: "CVE", Name: "Cabo Verde Escudo", MinorUnits: 2, Symbol: ""},
+	{Code: "CZK", Name: "Czech Koruna", MinorUnits: 2, Symbol: ""},
+	{Code: "DJF", Name: "Djibouti Franc", MinorUnits: 0, Symbol: ""},
+	{Code: "DKK", Name: "Danish Krone", MinorUnits: 2, Symbol: ""},
+	{Code: "DOP", Name: "Dominican Peso", MinorUnits: 2, Symbol: ""},
+	{Code: "DZD", Name: "Algerian Dinar", MinorUnits: 2, Symbol: ""},
+	{Code: "EGP", Name: "Egyptian Pound", MinorUnits: 2, Symbol: ""},
+	{Code: "ERN", Name: "Nakfa", MinorUnits: 2, Symbol: ""},
+	{Code: "ETB", Name: "Ethiopian Birr", MinorUnits: 2, Symbol: ""},
+	{Code: "EUR", Name: "Euro", MinorUnits: 2, Symbol: "€"},
+	{Code: "FJD", Name: "Fiji Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "FKP", Name: "Falkland Islands Pound", MinorUnits: 2, Symbol: ""},
+	{Code: "GBP", Name: "Pound Sterling", MinorUnits: 2, Symbol: "£"},
+	{Code: "GEL", Name: "Lari", MinorUnits: 2, Symbol: ""},
+	{Code: "GHS", Name: "Ghana Cedi", MinorUnits: 2, Symbol: ""},
+	{Code: "GIP", Name: "Gibraltar Pound", MinorUnits: 2, Symbol: ""},
+	{Code: "GMD", Name: "Dalasi", MinorUnits: 2, Symbol: ""},
+	{Code: "GNF", Name: "Guinean Franc", MinorUnits: 0, Symbol: ""},
+	{Code: "GTQ", Name: "Quetzal", MinorUnits: 2, Symbol: ""},
+	{Code: "GYD", Name: "Guyana Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "HKD", Name: "Hong Kong Dollar", MinorUnits: 2, Symbol: "HK$"},
+	{Code: "HNL", Name: "Lempira", MinorUnits: 2, Symbol: ""},
+	{Code: "HTG", Name: "Gourde", MinorUnits: 2, Symbol: ""},
+	{Code: "HUF", Name: "Forint", MinorUnits: 2, Symbol: ""},
+	{Code: "IDR", Name: "Rupiah", MinorUnits: 2, Symbol: ""},
+	{Code: "ILS", Name: "New Israeli Sheqel", MinorUnits: 2, Symbol: "₪"},
+	{Code: "INR", Name: "Indian Rupee", MinorUnits: 2, Symbol: "₹"},
+	{Code: "IQD", Name: "Iraqi Dinar", MinorUnits: 3, Symbol: ""},
+	{Code: "IRR", Name: "Iranian Rial", MinorUnits: 2, Symbol: ""},
+	{Code: "ISK", Name: "Iceland Krona", MinorUnits: 0, Symbol: ""},
+	{Code: "JMD", Name: "Jamaican Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "JOD", Name: "Jordanian Dinar", MinorUnits: 3, Symbol: ""},
+	{Code: "JPY", Name: "Yen", MinorUnits: 0, Symbol: "¥"},
+	{Code: "KES", Name: "Kenyan Shilling", MinorUnits: 2, Symbol: ""},
+	{Code: "KGS", Name: "Som", MinorUnits: 2, Symbol: ""},
+	{Code: "KHR", Name: "Riel", MinorUnits: 2, Symbol: ""},
+	{Code: "KMF", Name: "Comorian Franc", MinorUnits: 0, Symbol: ""},
+	{Code: "KPW", Name: "North Korean Won", MinorUnits: 2, Symbol: ""},
+	{Code: "KRW", Name: "Won", MinorUnits: 0, Symbol: "₩"},
+	{Code: "KWD", Name: "Kuwaiti Dinar", MinorUnits: 3, Symbol: ""},
+	{Code: "KYD", Name: "Cayman Islands Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "KZT", Name: "Tenge", MinorUnits: 2, Symbol: ""},
+	{Code: "LAK", Name: "Lao Kip", MinorUnits: 2, Symbol: ""},
+	{Code: "LBP", Name: "Lebanese Pound", MinorUnits: 2, Symbol: ""},
+	{Code: "LKR", Name: "Sri Lanka Rupee", MinorUnits: 2, Symbol: ""},
+	{Code: "LRD", Name: "Liberian Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "LSL", Name: "Loti", MinorUnits: 2, Symbol: ""},
+	{Code: "LYD", Name: "Libyan Dinar", MinorUnits: 3, Symbol: ""},
+	{Code: "MAD", Name: "Moroccan Dirham", MinorUnits: 2, Symbol: ""},
+	{Code: "MDL", Name: "Moldovan Leu", MinorUnits: 2, Symbol: ""},
+	{Code: "MGA", Name: "Malagasy Ariary", MinorUnits: 2, Symbol: ""},
+	{Code: "MKD", Name: "Denar", MinorUnits: 2, Symbol: ""},
+	{Code: "MMK", Name: "Kyat", MinorUnits: 2, Symbol: ""},
+	{Code: "MNT", Name: "Tugrik", MinorUnits: 2, Symbol: ""},
+	{Code: "MOP", Name: "Pataca", MinorUnits: 2, Symbol: ""},
+	{Code: "MRU", Name: "Ouguiya", MinorUnits: 2, Symbol: ""},
+	{Code: "MUR", Name: "Mauritius Rupee", MinorUnits: 2, Symbol: ""},
+	{Code: "MVR", Name: "Rufiyaa", MinorUnits: 2, Symbol: ""},
+	{Code: "MWK", Name: "Malawi Kwacha", MinorUnits: 2, Symbol: ""},
+	{Code: "MXN", Name: "Mexican Peso", MinorUnits: 2, Symbol: "MX$"},
+	{Code: "MYR", Name: "Malaysian Ringgit", MinorUnits: 2, Symbol: ""},
+	{Code: "MZN", Name: "Mozambique Metical", MinorUnits: 2, Symbol: ""},
+	{Code: "NAD", Name: "Namibia Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "NGN", Name: "Naira", MinorUnits: 2, Symbol: "₦"},
+	{Code: "NIO", Name: "Cordoba Oro", MinorUnits: 2, Symbol: ""},
+	{Code: "NOK", Name: "Norwegian Krone", MinorUnits: 2, Symbol: ""},
+	{Code: "NPR", Name: "Nepalese Rupee", MinorUnits: 2, Symbol: ""},
+	{Code: "NZD", Name: "New Zealand Dollar", MinorUnits: 2, Symbol: "NZ$"},
+	{Code: "OMR", Name: "Rial Omani", MinorUnits: 3, Symbol: ""},
+	{Code: "PAB", Name: "Balboa", MinorUnits: 2, Symbol: ""},
+	{Code: "PEN", Name: "Sol", MinorUnits: 2, Symbol: ""},
+	{Code: "PGK", Name: "Kina", MinorUnits: 2, Symbol: ""},
+	{Code: "PHP", Name: "Philippine Peso", MinorUnits: 2, Symbol: "₱"},
+	{Code: "PKR", Name: "Pakistan Rupee", MinorUnits: 2, Symbol: ""},
+	{Code: "PLN", Name: "Zloty", MinorUnits: 2, Symbol: ""},
+	{Code: "PYG", Name: "Guarani", MinorUnits: 0, Symbol: ""},
+	{Code: "QAR", Name: "Qatari Rial", MinorUnits: 2, Symbol: ""},
+	{Code: "RON", Name: "Romanian Leu", MinorUnits: 2, Symbol: ""},
+	{Code: "RSD", Name: "Serbian Dinar", MinorUnits: 2, Symbol: ""},
+	{Code: "RUB", Name: "Russian Ruble", MinorUnits: 2, Symbol: ""},
+	{Code: "RWF", Name: "Rwanda Franc", MinorUnits: 0, Symbol: ""},
+	{Code: "SAR", Name: "Saudi Riyal", MinorUnits: 2, Symbol: ""},
+	{Code: "SBD", Name: "Solomon Islands Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "SCR", Name: "Seychelles Rupee", MinorUnits: 2, Symbol: ""},
+	{Code: "SDG", Name: "Sudanese Pound", MinorUnits: 2, Symbol: ""},
+	{Code: "SEK", Name: "Swedish Krona", MinorUnits: 2, Symbol: ""},
+	{Code: "SGD", Name: "Singapore Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "SHP", Name: "Saint Helena Pound", MinorUnits: 2, Symbol: ""},
+	{Code: "SLE", Name: "Leone", MinorUnits: 2, Symbol: ""},
+	{Code: "SOS", Name: "Somali Shilling", MinorUnits: 2, Symbol: ""},
+	{Code: "SRD", Name: "Surinam Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "SSP", Name: "South Sudanese Pound", MinorUnits: 2, Symbol: ""},
+	{Code: "STN", Name: "Dobra", MinorUnits: 2, Symbol: ""},
+	{Code: "SVC", Name: "El Salvador Colon", MinorUnits: 2, Symbol: ""},
+	{Code: "SYP", Name: "Syrian Pound", MinorUnits: 2, Symbol: ""},
+	{Code: "SZL", Name: "Lilangeni", MinorUnits: 2, Symbol: ""},
+	{Code: "THB", Name: "Baht", MinorUnits: 2, Symbol: "฿"},
+	{Code: "TJS", Name: "Somoni", MinorUnits: 2, Symbol: ""},
+	{Code: "TMT", Name: "Turkmenistan New Manat", MinorUnits: 2, Symbol: ""},
+	{Code: "TND", Name: "Tunisian Dinar", MinorUnits: 3, Symbol: ""},
+	{Code: "TOP", Name: "Pa'anga", MinorUnits: 2, Symbol: ""},
+	{Code: "TRY", Name: "Turkish Lira", MinorUnits: 2, Symbol: ""},
+	{Code: "TTD", Name: "Trinidad and Tobago Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "TWD", Name: "New Taiwan Dollar", MinorUnits: 2, Symbol: "NT$"},
+	{Code: "TZS", Name: "Tanzanian Shilling", MinorUnits: 2, Symbol: ""},
+	{Code: "UAH", Name: "Hryvnia", MinorUnits: 2, Symbol: ""},
+	{Code: "UGX", Name: "Uganda Shilling", MinorUnits: 0, Symbol: ""},
+	{Code: "USD", Name: "US Dollar", MinorUnits: 2, Symbol: "$"},
+	{Code: "UYU", Name: "Peso Uruguayo", MinorUnits: 2, Symbol: ""},
+	{Code: "UZS", Name: "Uzbekistan Sum", MinorUnits: 2, Symbol: ""},
+	{Code: "VED", Name: "Bolívar Soberano", MinorUnits: 2, Symbol: ""},
+	{Code: "VES", Name: "Bolívar Soberano", MinorUnits: 2, Symbol: ""},
+	{Code: "VND", Name: "Dong", MinorUnits: 0, Symbol: "₫"},
+	{Code: "VUV", Name: "Vatu", MinorUnits: 0, Symbol: ""},
+	{Code: "WST", Name: "Tala", MinorUnits: 2, Symbol: ""},
+	{Code: "XAF", Name: "CFA Franc BEAC", MinorUnits: 0, Symbol: ""},
+	{Code: "XCD", Name: "East Caribbean Dollar", MinorUnits: 2, Symbol: ""},
+	{Code: "XCG", Name: "Caribbean Guilder", MinorUnits: 2, Symbol: ""},
+	{Code: "XOF", Name: "CFA Franc BCEAO", MinorUnits: 0, Symbol: ""},
+	{Code: "XPF", Name: "CFP Franc", MinorUnits: 0, Symbol: ""},
+	{Code: "YER", Name: "Yemeni Rial", MinorUnits: 2, Symbol: ""},
+	{Code: "ZAR", Name: "Rand", MinorUnits: 2, Symbol: ""},
+	{Code: "ZMW", Name: "Zambian Kwacha", MinorUnits: 2, Symbol: ""},
+	{Code: "ZWG", Name: "Zimbabwe Gold", MinorUnits: 2, Symbol: ""},
+}
+
+var currenciesByCode = func() map[string]*Currency {
+	byCode := make(map[string]*Currency, len(currencies))
+	for index := range currencies {
+		byCode[currencies[index].Code] = &currencies[index]
+	}
+	return byCode
+}()
+
+// findCurrency returns the currency with an ISO 4217 code.
+func findCurrency(code string) (*Currency, bool) {
+	currency, ok := currenciesByCode[code]
+	return currency, ok
+}
+
+// format formats an amount in minor units, like 1000 as $10.00.
+func (c *Currency) format(amount uint) string {
+	digits := strconv.FormatUint(uint64(amount), 10)
+	if c.MinorUnits > 0 {
+		for uint(len(digits)) <= c.MinorUnits {
+			digits = "0" + digits
+		}
+	}
+	split := uint(len(digits)) - c.MinorUnits
+	formatted := digits[:split]
+	if c.MinorUnits > 0 {
+		formatted += "." + digits[split:]
+	}
+	if c.Symbol != "" {
+		return c.Symbol + formatted
+	}
+	return c.Code + " " + formatted
+}
+
+var currency1_0_0PreSchema = func() string {
+	codes := make([]string, len(currencies))
+	for index, currency := range currencies {
+		codes[index] = currency.Code
+	}
+	schema := map[string]interface{}{
+		"$schema": "http://json-schema.org/schema#",
+		"$id":     "https://schemas.licensezero.com/1.0.0-pre/currency.json",
+		"title":   "ISO 4217 currency code",
+		"type":    "string",
+		"enum":    codes,
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}()
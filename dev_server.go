@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"io/ioutil"
+	"licensezero.com/cli2/devserver"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+const devServerUsage = `Run a fake licensing API for development.
+
+Usage:
+  licensezero dev-server --fixtures DIRECTORY [flags]
+
+Flags:
+  --listen ADDRESS       address to listen on (default "localhost:8080")
+  --url URL              API URL to put in receipts (default from requests)
+  --key NAME             signing key for receipts (default a new key)
+  --tls-cert FILE        serve HTTPS with a certificate
+  --tls-key FILE         private key for --tls-cert
+  --ca-out FILE          where to write the generated certificate
+                         (default dev-server.pem in the configuration
+                         directory)
+  --http                 serve plain HTTP, which the CLI refuses
+  --auto-complete        pay for orders as soon as they're placed
+  --latency DURATION     delay every response
+  --error-rate RATE      fail this fraction of requests, from 0 to 1
+  --rate-limit N         allow N requests per second
+
+The fixture directory holds offers/OFFERID.json, licensors/ID.json,
+receipts/*.json, and form.txt. Visit an order's checkout URL to pay
+for it.
+
+Without --tls-cert, the server makes a self-signed certificate for
+localhost, 127.0.0.1, and ::1, and writes it to --ca-out. To use the
+server from the CLI, allow it and trust the certificate in config.json:
+
+  {
+    "apis": ["https://localhost:8080"],
+    "caBundle": "/path/to/dev-server.pem",
+    "publicKeys": {"https://localhost:8080": "KEY"}
+  }
+
+where KEY is the key the server prints when it starts. Setting
+LICENSEZERO_CA_BUNDLE instead leaves config.json alone.
+`
+
+var devServerCommand = subcommand{
+	Summary: "Run a fake licensing API for development.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("dev-server", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, devServerUsage) }
+		fixtures := flagSet.String("fixtures", "", "")
+		listen := flagSet.String("listen", "localhost:8080", "")
+		url := flagSet.String("url", "", "")
+		keyName := flagSet.String("key", "", "")
+		tlsCert := flagSet.String("tls-cert", "", "")
+		tlsKey := flagSet.String("tls-key", "", "")
+		caOut := flagSet.String("ca-out", path.Join(env.Config, "dev-server.pem"), "")
+		plainHTTP := flagSet.Bool("http", false, "")
+		autoComplete := flagSet.Bool("auto-complete", false, "")
+		latency := flagSet.Duration("latency", 0, "")
+		errorRate := flagSet.Float64("error-rate", 0, "")
+		rateLimit := flagSet.Int("rate-limit", 0, "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 || *fixtures == "" ||
+			(*tlsCert == "") != (*tlsKey == "") || (*plainHTTP && *tlsCert != "") ||
+			*errorRate < 0 || *errorRate > 1 || *rateLimit < 0 {
+			flagSet.Usage()
+			return 1
+		}
+		var privateKey ed25519.PrivateKey
+		if *keyName == "" {
+			var err error
+			_, privateKey, err = ed25519.GenerateKey(nil)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not generate key:", err)
+				return 1
+			}
+		} else {
+			passphrase, err := readPassphrase(env, "Passphrase")
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not read passphrase:", err)
+				return 1
+			}
+			privateKey, err = readSigningKey(env.Config, *keyName, passphrase)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not read signing key:", err)
+				return 1
+			}
+		}
+		server := newDevServer(*fixtures, privateKey)
+		server.URL = *url
+		server.AutoComplete = *autoComplete
+		server.Latency = *latency
+		server.ErrorRate = *errorRate
+		server.RateLimit = *rateLimit
+		httpServer := &http.Server{Addr: *listen, Handler: server}
+		if !*plainHTTP && *tlsCert == "" {
+			certificate, certificatePEM, err := generateDevCertificate(*listen)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not generate certificate:", err)
+				return 1
+			}
+			err = os.MkdirAll(path.Dir(*caOut), 0700)
+			if err == nil {
+				err = ioutil.WriteFile(*caOut, certificatePEM, 0644)
+			}
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not write certificate:", err)
+				return 1
+			}
+			httpServer.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+			fmt.Fprintln(env.Stdout, "Wrote certificate to", *caOut)
+		}
+		fmt.Fprintln(env.Stdout, "Signing offers and receipts with key", server.PublicKey)
+		fmt.Fprintln(env.Stdout, "Listening on", *listen)
+		var err error
+		if *plainHTTP {
+			err = httpServer.ListenAndServe()
+		} else {
+			err = httpServer.ListenAndServeTLS(*tlsCert, *tlsKey)
+		}
+		fmt.Fprintln(env.Stderr, "Server stopped:", err)
+		return 1
+	},
+}
+
+// generateDevCertificate makes a self-signed certificate for loopback
+// addresses and the host of a listen address, returning it with its
+// PEM encoding.
+func generateDevCertificate(listen string) (tls.Certificate, []byte, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "licensezero dev-server"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, _, err := net.SplitHostPort(listen); err == nil && host != "" && host != "localhost" {
+		if ip := net.ParseIP(host); ip != nil {
+			if !ip.IsLoopback() {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	certificate := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: privateKey}
+	return certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// newDevServer returns a fake licensing API that signs offers and
+// receipts with a private key.
+func newDevServer(fixtures string, privateKey ed25519.PrivateKey) *devserver.Server {
+	server := devserver.New(fixtures, func(request devserver.IssueRequest) ([]byte, error) {
+		return issueDevReceipt(request, privateKey)
+	}, newUUID)
+	server.SignOffer = func(api string, offerID string, offer []byte) ([]byte, error) {
+		return signOffer(api, offerID, offer, privateKey)
+	}
+	server.PublicKey = hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	return server
+}
+
+// signOffer adds an API signature to an offer.
+func signOffer(api string, offerID string, data []byte, privateKey ed25519.PrivateKey) ([]byte, error) {
+	var object map[string]interface{}
+	err := json.Unmarshal(data, &object)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := offerSigningData(api, offerID, object)
+	if err != nil {
+		return nil, err
+	}
+	object["signature"] = hex.EncodeToString(ed25519.Sign(privateKey, signed))
+	return json.Marshal(object)
+}
+
+// issueDevReceipt signs a receipt for a development server order.
+func issueDevReceipt(request devserver.IssueRequest, privateKey ed25519.PrivateKey) ([]byte, error) {
+	var unstructured interface{}
+	err := json.Unmarshal(request.Offer, &unstructured)
+	if err != nil {
+		return nil, err
+	}
+	offer, err := ParseOffer(unstructured)
+	if err != nil {
+		return nil, err
+	}
+	var license license1_0_0Pre
+	license.Form = request.Form
+	values := &license.Values
+	values.API = request.API
+	values.OfferID = request.OfferID
+	values.OrderID = request.OrderID
+	values.Effective = time.Now().UTC().Format(time.RFC3339)
+	values.Relicense = request.Relicense
+	err = json.Unmarshal(request.Licensor, &values.Licensor)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(request.Licensee, &values.Licensee)
+	if err != nil {
+		return nil, err
+	}
+	var price Price
+	if request.Relicense {
+		price = offer.Pricing.Relicense
+		if price.Currency == "" {
+			return nil, errors.New("not offered for relicensing")
+		}
+	} else {
+		seats := request.Seats
+		if seats == 0 {
+			seats = 1
+		}
+		price, err = offer.Pricing.PriceFor(seats)
+		if err != nil {
+			return nil, err
+		}
+	}
+	values.Price = &price
+	receipt, err := signV1Receipt(license, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(receipt)
+}
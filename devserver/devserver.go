@@ -0,0 +1,458 @@
+// Package devserver implements a fake licensing API for development
+// and testing. It serves offers, one at a time or in batches, orders,
+// receipts, and its signing key from a fixture directory, and can
+// simulate latency, errors, and rate limits.
+//
+// A fixture directory contains:
+//
+//	offers/OFFERID.json          offers, signed with SignOffer if set
+//	licensors/LICENSORID.json    licensor details for receipts
+//	receipts/*.json              receipts issued before the server started
+//	form.txt                     license form for new receipts
+//
+// Orders are kept in memory. Visiting an order's checkout URL pays
+// for it, issuing a receipt for each offer with the Issue function.
+package devserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IssueRequest describes a receipt to issue for an offer in a paid
+// order.
+type IssueRequest struct {
+	API       string
+	OrderID   string
+	OfferID   string
+	Offer     json.RawMessage
+	Licensor  json.RawMessage
+	Licensee  json.RawMessage
+	Seats     uint
+	Relicense bool
+	Form      string
+}
+
+// IssueFunc signs a receipt and returns it as JSON.
+type IssueFunc func(request IssueRequest) ([]byte, error)
+
+// IDFunc returns a new, unique order ID.
+type IDFunc func() (string, error)
+
+// Server is a fake licensing API.
+type Server struct {
+	// Fixtures is the path of the fixture directory.
+	Fixtures string
+	// Issue signs receipts for paid orders.
+	Issue IssueFunc
+	// NewOrderID returns IDs for new orders.
+	NewOrderID IDFunc
+	// SignOffer, if set, signs offers before they're served, as the
+	// offer with offerID from the API at api.
+	SignOffer func(api string, offerID string, offer []byte) ([]byte, error)
+	// PublicKey is the hex-encoded public key receipts and offers are
+	// signed with.
+	PublicKey string
+	// URL is the API's URL in receipts and checkout links. If empty,
+	// it comes from each request's Host header.
+	URL string
+	// DisableBatch turns off batch offer lookup, like older APIs.
+	DisableBatch bool
+	// AutoComplete pays for orders as soon as they're placed.
+	AutoComplete bool
+	// Latency delays every response.
+	Latency time.Duration
+	// ErrorRate is the fraction of requests, from 0 to 1, that fail
+	// with a server error.
+	ErrorRate float64
+	// RateLimit is the number of requests allowed each second. Zero
+	// means no limit.
+	RateLimit int
+
+	mutex       sync.Mutex
+	orders      map[string]*order
+	issued      []json.RawMessage
+	random      *rand.Rand
+	windowStart time.Time
+	windowCount int
+}
+
+type order struct {
+	OrderID   string            `json:"orderID"`
+	Checkout  string            `json:"checkoutURL"`
+	Status    string            `json:"status"`
+	Receipts  []json.RawMessage `json:"receipts"`
+	api       string
+	licensee  json.RawMessage
+	seats     uint
+	relicense bool
+	offers    []string
+}
+
+// Order statuses.
+const (
+	pending  = "pending"
+	complete = "complete"
+)
+
+// New returns a server for a fixture directory.
+func New(fixtures string, issue IssueFunc, newOrderID IDFunc) *Server {
+	return &Server{Fixtures: fixtures, Issue: issue, NewOrderID: newOrderID}
+}
+
+var validID = regexp.MustCompile(`^[0-9a-f-]+$`)
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Latency > 0 {
+		time.Sleep(s.Latency)
+	}
+	if s.rateLimited() {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "rate limited")
+		return
+	}
+	if s.simulateFailure() {
+		writeError(w, http.StatusInternalServerError, "simulated failure")
+		return
+	}
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(segments) == 2 && segments[0] == "offers" && segments[1] == "lookup" &&
+		r.Method == "POST" && !s.DisableBatch:
+		s.lookupOffers(w, r)
+	case len(segments) == 2 && segments[0] == "offers" && r.Method == "GET":
+		s.getOffer(w, r, segments[1])
+	case len(segments) == 1 && segments[0] == "orders" && r.Method == "POST":
+		s.createOrder(w, r)
+	case len(segments) == 2 && segments[0] == "orders" && r.Method == "GET":
+		s.getOrder(w, segments[1])
+	case len(segments) == 2 && segments[0] == "checkout":
+		s.checkout(w, segments[1])
+	case len(segments) == 1 && segments[0] == "receipts" && r.Method == "GET":
+		s.getReceipts(w, r)
+	case len(segments) == 1 && segments[0] == "key" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, map[string]string{"publicKey": s.PublicKey})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) rateLimited() bool {
+	if s.RateLimit <= 0 {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	if now.Sub(s.windowStart) >= time.Second {
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	s.windowCount++
+	return s.windowCount > s.RateLimit
+}
+
+func (s *Server) simulateFailure() bool {
+	if s.ErrorRate <= 0 {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.random == nil {
+		s.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return s.random.Float64() < s.ErrorRate
+}
+
+func (s *Server) readOffer(offerID string) ([]byte, error) {
+	if !validID.MatchString(offerID) {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.ReadFile(path.Join(s.Fixtures, "offers", offerID+".json"))
+}
+
+func (s *Server) getOffer(w http.ResponseWriter, r *http.Request, offerID string) {
+	data, err := s.readOffer(offerID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeError(w, http.StatusNotFound, "no such offer")
+		} else {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	if s.SignOffer != nil {
+		data, err = s.SignOffer(s.baseURL(r), offerID, data)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// lookupOffers serves several offers at once, omitting any that don't
+// exist.
+func (s *Server) lookupOffers(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		OfferIDs []string `json:"offerIDs"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid lookup")
+		return
+	}
+	offers := make(map[string]json.RawMessage)
+	for _, offerID := range request.OfferIDs {
+		data, err := s.readOffer(offerID)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if s.SignOffer != nil {
+			data, err = s.SignOffer(s.baseURL(r), offerID, data)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		offers[offerID] = data
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"offers": offers})
+}
+
+func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Licensee  json.RawMessage `json:"licensee"`
+		Seats     uint            `json:"seats"`
+		Relicense bool            `json:"relicense"`
+		Offers    []string        `json:"offers"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || len(request.Licensee) == 0 || len(request.Offers) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid order")
+		return
+	}
+	for _, offerID := range request.Offers {
+		if _, err := s.readOffer(offerID); err != nil {
+			writeError(w, http.StatusBadRequest, "no such offer: "+offerID)
+			return
+		}
+	}
+	if s.NewOrderID == nil {
+		writeError(w, http.StatusInternalServerError, "no order ID generator")
+		return
+	}
+	orderID, err := s.NewOrderID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api := s.baseURL(r)
+	created := &order{
+		OrderID:   orderID,
+		Checkout:  api + "/checkout/" + orderID,
+		Status:    pending,
+		Receipts:  []json.RawMessage{},
+		api:       api,
+		licensee:  request.Licensee,
+		seats:     request.Seats,
+		relicense: request.Relicense,
+		offers:    request.Offers,
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.orders == nil {
+		s.orders = make(map[string]*order)
+	}
+	s.orders[orderID] = created
+	if s.AutoComplete {
+		err = s.complete(created)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, orderID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	found, ok := s.orders[orderID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "no such order")
+		return
+	}
+	writeJSON(w, http.StatusOK, found)
+}
+
+func (s *Server) checkout(w http.ResponseWriter, orderID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	found, ok := s.orders[orderID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "no such order")
+		return
+	}
+	if found.Status == pending {
+		err := s.complete(found)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "Order %s is paid.\n", orderID)
+}
+
+// complete pays for an order, issuing its receipts. The caller must
+// hold the mutex.
+func (s *Server) complete(paid *order) error {
+	if s.Issue == nil {
+		return errors.New("no receipt issuer")
+	}
+	form, err := ioutil.ReadFile(path.Join(s.Fixtures, "form.txt"))
+	if err != nil {
+		return err
+	}
+	var receipts []json.RawMessage
+	for _, offerID := range paid.offers {
+		offer, err := s.readOffer(offerID)
+		if err != nil {
+			return err
+		}
+		var parsed struct {
+			LicensorID string `json:"licensorID"`
+		}
+		err = json.Unmarshal(offer, &parsed)
+		if err != nil {
+			return err
+		}
+		if !validID.MatchString(parsed.LicensorID) {
+			return errors.New("invalid licensorID in offer " + offerID)
+		}
+		licensor, err := ioutil.ReadFile(path.Join(s.Fixtures, "licensors", parsed.LicensorID+".json"))
+		if err != nil {
+			return err
+		}
+		receipt, err := s.Issue(IssueRequest{
+			API:       paid.api,
+			OrderID:   paid.OrderID,
+			OfferID:   offerID,
+			Offer:     offer,
+			Licensor:  licensor,
+			Licensee:  paid.licensee,
+			Seats:     paid.seats,
+			Relicense: paid.relicense,
+			Form:      string(form),
+		})
+		if err != nil {
+			return err
+		}
+		receipts = append(receipts, receipt)
+	}
+	paid.Receipts = receipts
+	paid.Status = complete
+	s.issued = append(s.issued, receipts...)
+	return nil
+}
+
+// getReceipts serves receipts for a licensee e-mail address or an
+// order, from fixtures and paid orders.
+func (s *Server) getReceipts(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	orderID := r.URL.Query().Get("order")
+	if email == "" && orderID == "" {
+		writeError(w, http.StatusBadRequest, "email or order required")
+		return
+	}
+	receipts, err := s.fixtureReceipts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.mutex.Lock()
+	receipts = append(receipts, s.issued...)
+	s.mutex.Unlock()
+	matching := []json.RawMessage{}
+	for _, receipt := range receipts {
+		var parsed struct {
+			License struct {
+				Values struct {
+					OrderID  string `json:"orderID"`
+					Licensee struct {
+						EMail string `json:"email"`
+					} `json:"licensee"`
+				} `json:"values"`
+			} `json:"license"`
+		}
+		if json.Unmarshal(receipt, &parsed) != nil {
+			continue
+		}
+		values := parsed.License.Values
+		if (email == "" || strings.EqualFold(values.Licensee.EMail, email)) &&
+			(orderID == "" || values.OrderID == orderID) {
+			matching = append(matching, receipt)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"receipts": matching})
+}
+
+func (s *Server) fixtureReceipts() (receipts []json.RawMessage, err error) {
+	directory := path.Join(s.Fixtures, "receipts")
+	entries, err := ioutil.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(path.Join(directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, data)
+	}
+	return
+}
+
+func (s *Server) baseURL(r *http.Request) string {
+	if s.URL != "" {
+		return strings.TrimRight(s.URL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,187 @@
+package devserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const offerID = "9aab7058-599a-43db-9449-5fc0971ecbfa"
+
+var orderCount uint64
+
+func fakeOrderID() (string, error) {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012x", atomic.AddUint64(&orderCount, 1)), nil
+}
+
+func fakeIssue(request IssueRequest) ([]byte, error) {
+	var licensee interface{}
+	err := json.Unmarshal(request.Licensee, &licensee)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{
+		"license": map[string]interface{}{
+			"values": map[string]interface{}{
+				"api":      request.API,
+				"offerID":  request.OfferID,
+				"orderID":  request.OrderID,
+				"licensee": licensee,
+			},
+		},
+	})
+}
+
+func TestOffers(t *testing.T) {
+	server := httptest.NewServer(New("testdata", fakeIssue, fakeOrderID))
+	defer server.Close()
+	response, err := http.Get(server.URL + "/offers/" + offerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var offer struct {
+		LicensorID string `json:"licensorID"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&offer)
+	if err != nil || offer.LicensorID != "59e70a4d-ffee-4e9d-a526-7a9ff9161664" {
+		t.Errorf("served %+v", offer)
+	}
+	for _, missing := range []string{"00000000-0000-4000-8000-000000000000", "..%2Fform.txt"} {
+		response, err = http.Get(server.URL + "/offers/" + missing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: responded %d", missing, response.StatusCode)
+		}
+	}
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	server := httptest.NewServer(New("testdata", fakeIssue, fakeOrderID))
+	defer server.Close()
+	body := []byte(`{
+  "licensee": {"name": "Joe", "email": "joe@example.com", "jurisdiction": "US-TX"},
+  "offers": ["` + offerID + `"]
+}`)
+	response, err := http.Post(server.URL+"/orders", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created struct {
+		OrderID  string `json:"orderID"`
+		Checkout string `json:"checkoutURL"`
+		Status   string `json:"status"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&created)
+	response.Body.Close()
+	if err != nil || created.Status != pending {
+		t.Fatalf("created %+v", created)
+	}
+
+	response, err = http.Get(created.Checkout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	response, err = http.Get(server.URL + "/orders/" + created.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paid struct {
+		Status   string            `json:"status"`
+		Receipts []json.RawMessage `json:"receipts"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&paid)
+	response.Body.Close()
+	if err != nil || paid.Status != complete || len(paid.Receipts) != 1 {
+		t.Fatalf("paid %+v", paid)
+	}
+
+	for _, query := range []string{"email=JOE@example.com", "order=" + created.OrderID} {
+		response, err = http.Get(server.URL + "/receipts?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found struct {
+			Receipts []json.RawMessage `json:"receipts"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&found)
+		response.Body.Close()
+		if err != nil || len(found.Receipts) != 1 {
+			t.Errorf("%s: found %d receipts", query, len(found.Receipts))
+		}
+	}
+}
+
+func TestSimulatedFaults(t *testing.T) {
+	failing := New("testdata", fakeIssue, fakeOrderID)
+	failing.ErrorRate = 1
+	server := httptest.NewServer(failing)
+	response, err := http.Get(server.URL + "/offers/" + offerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("responded %d instead of failing", response.StatusCode)
+	}
+	server.Close()
+
+	limited := New("testdata", fakeIssue, fakeOrderID)
+	limited.RateLimit = 1
+	server = httptest.NewServer(limited)
+	defer server.Close()
+	var statuses []int
+	for i := 0; i < 2; i++ {
+		response, err := http.Get(server.URL + "/offers/" + offerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		statuses = append(statuses, response.StatusCode)
+	}
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusTooManyRequests {
+		t.Errorf("responded %v", statuses)
+	}
+}
+
+func TestLookupOffers(t *testing.T) {
+	server := httptest.NewServer(New("testdata", fakeIssue, fakeOrderID))
+	defer server.Close()
+	body := []byte(`{"offerIDs": ["` + offerID + `", "00000000-0000-4000-8000-000000000000"]}`)
+	response, err := http.Post(server.URL+"/offers/lookup", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found struct {
+		Offers map[string]json.RawMessage `json:"offers"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&found)
+	response.Body.Close()
+	if err != nil || len(found.Offers) != 1 || found.Offers[offerID] == nil {
+		t.Errorf("found %v", found.Offers)
+	}
+
+	disabled := New("testdata", fakeIssue, fakeOrderID)
+	disabled.DisableBatch = true
+	server = httptest.NewServer(disabled)
+	defer server.Close()
+	response, err = http.Post(server.URL+"/offers/lookup", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("responded %d with batch lookup disabled", response.StatusCode)
+	}
+}
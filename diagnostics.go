@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Sources of diagnostics.
+const (
+	diagnosticFinder   = "finder"
+	diagnosticParser   = "parser"
+	diagnosticNetwork  = "network"
+	diagnosticReceipt  = "receipt"
+	diagnosticAccount  = "account"
+	diagnosticIdentity = "identity"
+)
+
+// Diagnostic records a problem that CompileInventory worked around.
+type Diagnostic struct {
+	// Path is the file or directory with the problem, if known.
+	Path string
+	// Source is what ran into the problem: a finder, a parser, the
+	// network, or reading receipts, accounts, or identities.
+	Source string
+	Cause  error
+}
+
+func (d Diagnostic) String() string {
+	if d.Path == "" {
+		return d.Source + ": " + d.Cause.Error()
+	}
+	return d.Source + ": " + d.Path + ": " + d.Cause.Error()
+}
+
+// fileError is an error about a particular file.
+type fileError struct {
+	Path string
+	Err  error
+}
+
+func (e *fileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// diagnose makes a diagnostic for an error, taking the path from the
+// error if it has one.
+func diagnose(source string, err error) Diagnostic {
+	var withPath *fileError
+	if errors.As(err, &withPath) {
+		return Diagnostic{Path: withPath.Path, Source: source, Cause: withPath.Err}
+	}
+	var pathError *os.PathError
+	if errors.As(err, &pathError) {
+		return Diagnostic{Path: pathError.Path, Source: source, Cause: pathError.Err}
+	}
+	return Diagnostic{Source: source, Cause: err}
+}
+
+// reportDiagnostics prints an inventory's diagnostics as warnings or,
+// if strict, as errors, reporting whether the command should stop.
+func reportDiagnostics(stderr io.Writer, inventory *Inventory, strict bool) (stop bool) {
+	count := len(inventory.Diagnostics)
+	if count == 0 {
+		return false
+	}
+	label, summary := "Warning", "warning"
+	if strict {
+		label, summary = "Error", "error"
+	}
+	for _, diagnostic := range inventory.Diagnostics {
+		fmt.Fprintf(stderr, "%s: %s\n", label, diagnostic)
+	}
+	if count != 1 {
+		summary += "s"
+	}
+	if strict {
+		fmt.Fprintf(stderr, "%d %s.\n", count, summary)
+		return true
+	}
+	fmt.Fprintf(stderr, "%d %s. Use --strict to treat them as errors.\n", count, summary)
+	return false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"time"
+)
+
+// exchangeRates are currency exchange rates as of a date, each giving
+// the number of units of a currency that one unit of the base currency
+// buys. Rates come from a file the user supplies, never from a remote
+// service.
+type exchangeRates struct {
+	Date  string
+	Base  string
+	Rates map[string]*big.Rat
+}
+
+type exchangeRatesFile struct {
+	Date  string                 `json:"date"`
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// readExchangeRates reads a rate file like:
+//
+//	{
+//	  "date": "2026-10-01",
+//	  "base": "EUR",
+//	  "rates": {"USD": 1.08, "GBP": 0.86}
+//	}
+func readExchangeRates(filePath string) (*exchangeRates, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseExchangeRates(data)
+}
+
+func parseExchangeRates(data []byte) (*exchangeRates, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var file exchangeRatesFile
+	err := decoder.Decode(&file)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse("2006-01-02", file.Date); err != nil {
+		return nil, errors.New("invalid rate date: " + file.Date)
+	}
+	if _, ok := findCurrency(file.Base); !ok {
+		return nil, errors.New("unknown base currency: " + file.Base)
+	}
+	rates := &exchangeRates{
+		Date:  file.Date,
+		Base:  file.Base,
+		Rates: map[string]*big.Rat{file.Base: big.NewRat(1, 1)},
+	}
+	for code, number := range file.Rates {
+		if _, ok := findCurrency(code); !ok {
+			return nil, errors.New("unknown currency: " + code)
+		}
+		rate, ok := new(big.Rat).SetString(number.String())
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %s", code, number)
+		}
+		if code == file.Base && rate.Cmp(big.NewRat(1, 1)) != 0 {
+			return nil, fmt.Errorf("rate for base currency %s must be 1", code)
+		}
+		rates.Rates[code] = rate
+	}
+	return rates, nil
+}
+
+// Convert converts a price to another currency, rounding half up to
+// the nearest minor unit.
+func (r *exchangeRates) Convert(price Price, to string) (Price, error) {
+	from, ok := findCurrency(price.Currency)
+	if !ok {
+		return Price{}, errors.New("unknown currency: " + price.Currency)
+	}
+	target, ok := findCurrency(to)
+	if !ok {
+		return Price{}, errors.New("unknown currency: " + to)
+	}
+	fromRate, ok := r.Rates[from.Code]
+	if !ok {
+		return Price{}, errors.New("no exchange rate for " + from.Code)
+	}
+	toRate, ok := r.Rates[target.Code]
+	if !ok {
+		return Price{}, errors.New("no exchange rate for " + target.Code)
+	}
+	amount := new(big.Rat).SetInt(new(big.Int).SetUint64(uint64(price.Amount)))
+	amount.Quo(amount, new(big.Rat).SetInt(powerOfTen(from.MinorUnits)))
+	amount.Quo(amount, fromRate)
+	amount.Mul(amount, toRate)
+	amount.Mul(amount, new(big.Rat).SetInt(powerOfTen(target.MinorUnits)))
+	// Round half up: floor(amount + 1/2).
+	amount.Add(amount, big.NewRat(1, 2))
+	rounded := new(big.Int).Quo(amount.Num(), amount.Denom())
+	if !rounded.IsUint64() || uint64(uint(rounded.Uint64())) != rounded.Uint64() {
+		return Price{}, errPriceOverflow
+	}
+	return Price{Amount: uint(rounded.Uint64()), Currency: target.Code}, nil
+}
+
+func powerOfTen(exponent uint) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestExchangeRatesConvert(t *testing.T) {
+	rates, err := parseExchangeRates([]byte(`{
+  "date": "2026-10-01",
+  "base": "EUR",
+  "rates": {"USD": 1.08, "JPY": 160, "KWD": 0.33}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors := []struct {
+		price    Price
+		to       string
+		expected Price
+	}{
+		{Price{Amount: 1000, Currency: "EUR"}, "USD", Price{Amount: 1080, Currency: "USD"}},
+		{Price{Amount: 1080, Currency: "USD"}, "EUR", Price{Amount: 1000, Currency: "EUR"}},
+		{Price{Amount: 1000, Currency: "USD"}, "JPY", Price{Amount: 1481, Currency: "JPY"}},
+		{Price{Amount: 1000, Currency: "EUR"}, "KWD", Price{Amount: 3300, Currency: "KWD"}},
+		{Price{Amount: 1, Currency: "USD"}, "EUR", Price{Amount: 1, Currency: "EUR"}},
+	}
+	for _, vector := range vectors {
+		converted, err := rates.Convert(vector.price, vector.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if converted != vector.expected {
+			t.Errorf("%v to %s: expected %v, got %v", vector.price, vector.to, vector.expected, converted)
+		}
+	}
+	if _, err := rates.Convert(Price{Amount: 100, Currency: "GBP"}, "EUR"); err == nil {
+		t.Error("converted without a rate")
+	}
+}
+
+func TestExchangeRatesInvalid(t *testing.T) {
+	for _, input := range []string{
+		`{"base": "EUR", "rates": {"USD": 1.08}}`,
+		`{"date": "2026-10-01", "base": "XYZ", "rates": {}}`,
+		`{"date": "2026-10-01", "base": "EUR", "rates": {"USD": 0}}`,
+		`{"date": "2026-10-01", "base": "EUR", "rates": {"USD": -1}}`,
+		`{"date": "2026-10-01", "base": "EUR", "rates": {"XYZ": 1}}`,
+	} {
+		if _, err := parseExchangeRates([]byte(input)); err == nil {
+			t.Errorf("accepted %s", input)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// writeFileAtomically writes a file by writing a temporary file in the
+// same directory and renaming it into place, so readers never see a
+// partially written file.
+func writeFileAtomically(filePath string, data []byte, mode os.FileMode) error {
+	temporary, err := ioutil.TempFile(path.Dir(filePath), "."+path.Base(filePath)+".")
+	if err != nil {
+		return err
+	}
+	temporaryPath := temporary.Name()
+	_, err = temporary.Write(data)
+	if closeError := temporary.Close(); err == nil {
+		err = closeError
+	}
+	if err == nil {
+		err = os.Chmod(temporaryPath, mode)
+	}
+	if err == nil {
+		err = os.Rename(temporaryPath, filePath)
+	}
+	if err != nil {
+		os.Remove(temporaryPath)
+	}
+	return err
+}
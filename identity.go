@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"licensezero.com/cli2/jurisdiction"
+	"os"
+	"path"
+	"strings"
+)
+
+// ReadIdentities reads all licensee identities in the configuration
+// directory.
+func ReadIdentities(configPath string) (identities []Licensee, errors []error, err error) {
+	directoryPath := path.Join(configPath, "identities")
+	entries, directoryReadError := ioutil.ReadDir(directoryPath)
+	if directoryReadError != nil {
+		if os.IsNotExist(directoryReadError) {
+			return
+		}
+		return nil, nil, directoryReadError
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		filePath := path.Join(configPath, "identities", name)
+		identity, err := readIdentity(filePath)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			identities = append(identities, *identity)
+		}
+	}
+	return
+}
+
+func readIdentity(filePath string) (*Licensee, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var identity Licensee
+	err = json.Unmarshal(data, &identity)
+	if err != nil {
+		return nil, &fileError{filePath, err}
+	}
+	err = validateIdentity(&identity)
+	if err != nil {
+		return nil, &fileError{filePath, err}
+	}
+	return &identity, nil
+}
+
+func identityPath(configPath string, email string) string {
+	return path.Join(configPath, "identities", strings.ToLower(email)+".json")
+}
+
+func writeIdentity(configPath string, identity *Licensee) error {
+	data, err := json.MarshalIndent(identity, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Join(configPath, "identities"), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(identityPath(configPath, identity.EMail), data, 0600)
+}
+
+const licensee1_0_0PreSchema = `{
+  "$schema": "http://json-schema.org/schema#",
+  "$id": "https://schemas.licensezero.com/1.0.0-pre/licensee.json",
+  "title": "licensee identity",
+  "type": "object",
+  "required": [
+    "email",
+    "jurisdiction",
+    "name"
+  ],
+  "properties": {
+    "email": {
+      "type": "string",
+      "format": "email"
+    },
+    "jurisdiction": {
+      "$ref": "https://schemas.licensezero.com/1.0.0-pre/jurisdiction.json"
+    },
+    "name": {
+      "$ref": "https://schemas.licensezero.com/1.0.0-pre/name.json"
+    }
+  }
+}`
+
+func validateIdentity(identity *Licensee) error {
+	if err := checkIdentityEMail(identity.EMail); err != nil {
+		return err
+	}
+	result, err := schemas.validate(schemaID("1.0.0-pre", "licensee"), identity)
+	if err != nil {
+		return err
+	}
+	if !result.Valid() {
+		return schemaErrors(result)
+	}
+	return nil
+}
+
+// checkIdentityEMail refuses e-mail addresses that can't safely name
+// an identity file.
+func checkIdentityEMail(email string) error {
+	if strings.ContainsAny(email, "/\\") || strings.Contains(email, "..") {
+		return errors.New("invalid e-mail address")
+	}
+	return nil
+}
+
+// matchesIdentity reports whether a receipt's licensee is one of the
+// configured identities.
+func matchesIdentity(licensee Licensee, identities []Licensee) bool {
+	for _, identity := range identities {
+		if strings.EqualFold(licensee.EMail, identity.EMail) &&
+			normalizeName(licensee.Name) == normalizeName(identity.Name) &&
+			licensee.Jurisdiction == identity.Jurisdiction {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+const identifyUsage = `Configure the identities you buy licenses as.
+
+Usage:
+  licensezero identify --name NAME --email EMAIL --jurisdiction CODE
+  licensezero identify --list
+  licensezero identify --remove EMAIL
+
+Jurisdictions are ISO 3166-2 codes, like US-CA or DE-BE, or their
+names, like California or Berlin.
+
+Receipts count toward coverage only when their licensee matches
+an identity.
+`
+
+var identifyCommand = subcommand{
+	Summary: "Configure licensee identities.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("identify", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, identifyUsage) }
+		name := flagSet.String("name", "", "")
+		email := flagSet.String("email", "", "")
+		jurisdiction := flagSet.String("jurisdiction", "", "")
+		list := flagSet.Bool("list", false, "")
+		remove := flagSet.String("remove", "", "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 {
+			flagSet.Usage()
+			return 1
+		}
+		if *list {
+			identities, _, err := ReadIdentities(env.Config)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not read identities:", err)
+				return 1
+			}
+			for _, identity := range identities {
+				fmt.Fprintf(env.Stdout, "%s <%s> (%s)\n", identity.Name, identity.EMail, identity.Jurisdiction)
+			}
+			return 0
+		}
+		if *remove != "" {
+			if err := checkIdentityEMail(*remove); err != nil {
+				fmt.Fprintln(env.Stderr, "Could not remove identity:", err)
+				return 1
+			}
+			err := os.Remove(identityPath(env.Config, *remove))
+			if err != nil {
+				if os.IsNotExist(err) {
+					fmt.Fprintln(env.Stderr, "No identity for", *remove)
+				} else {
+					fmt.Fprintln(env.Stderr, "Could not remove identity:", err)
+				}
+				return 1
+			}
+			return 0
+		}
+		if *name == "" || *email == "" || *jurisdiction == "" {
+			flagSet.Usage()
+			return 1
+		}
+		code, err := resolveJurisdiction(*jurisdiction)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, err)
+			return 1
+		}
+		identity := Licensee{
+			EMail:        *email,
+			Jurisdiction: code,
+			Name:         *name,
+		}
+		err = validateIdentity(&identity)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Invalid identity:", err)
+			return 1
+		}
+		err = writeIdentity(env.Config, &identity)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not write identity:", err)
+			return 1
+		}
+		return 0
+	},
+}
+
+// resolveJurisdiction finds the code for a jurisdiction given by code
+// or name, explaining what might have been meant if it can't.
+func resolveJurisdiction(input string) (string, error) {
+	if found, ok := jurisdiction.ByCode(input); ok {
+		return found.Code, nil
+	}
+	named := jurisdiction.ByName(input)
+	if len(named) == 1 {
+		return named[0].Code, nil
+	}
+	if len(named) > 1 {
+		return "", errors.New("More than one jurisdiction is named " + input + ". Use one of these codes:\n" + listJurisdictions(named))
+	}
+	if country, ok := jurisdiction.FindCountry(input); ok {
+		return "", errors.New(country.Name + " is a country. Use the code for one of its jurisdictions:\n" + listJurisdictions(country.Jurisdictions()))
+	}
+	suggestions := jurisdiction.Suggest(input, 5)
+	if len(suggestions) != 0 {
+		return "", errors.New("Unknown jurisdiction " + input + ". Did you mean:\n" + listJurisdictions(suggestions))
+	}
+	return "", errors.New("Unknown jurisdiction " + input + ". Use an ISO 3166-2 code, like US-CA.")
+}
+
+func listJurisdictions(list []jurisdiction.Jurisdiction) string {
+	lines := make([]string, len(list))
+	for index, item := range list {
+		lines[index] = "  " + item.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestIdentify(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		code := run([]string{
+			"identify",
+			"--name", "Joe Licensee",
+			"--email", "licensee@example.com",
+			"--jurisdiction", "US-TX",
+		}, env)
+		if code != 0 {
+			t.Fatal(stderr.String())
+		}
+
+		env, stdout, _ := newTestEnvironment(directory, "", nil)
+		if run([]string{"identify", "--list"}, env) != 0 {
+			t.Fatal("could not list identities")
+		}
+		if !strings.Contains(stdout.String(), "Joe Licensee <licensee@example.com> (US-TX)") {
+			t.Error("did not list identity")
+		}
+
+		identities, identityErrors, err := ReadIdentities(directory)
+		if err != nil || len(identityErrors) != 0 {
+			t.Fatal("could not read identities")
+		}
+		if len(identities) != 1 || identities[0].Name != "Joe Licensee" {
+			t.Error("did not store identity")
+		}
+
+		env, _, _ = newTestEnvironment(directory, "", nil)
+		if run([]string{"identify", "--remove", "licensee@example.com"}, env) != 0 {
+			t.Error("could not remove identity")
+		}
+		identities, _, _ = ReadIdentities(directory)
+		if len(identities) != 0 {
+			t.Error("did not remove identity")
+		}
+	})
+}
+
+func TestIdentifyInvalidJurisdiction(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		code := run([]string{
+			"identify",
+			"--name", "Joe Licensee",
+			"--email", "licensee@example.com",
+			"--jurisdiction", "US-XX",
+		}, env)
+		if code == 0 {
+			t.Error("accepted invalid jurisdiction")
+		}
+		if !strings.Contains(stderr.String(), "Unknown jurisdiction US-XX. Did you mean:") {
+			t.Errorf("did not suggest jurisdictions:\n%s", stderr.String())
+		}
+	})
+}
+
+func TestIdentifyJurisdictionName(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		code := run([]string{
+			"identify",
+			"--name", "Joe Licensee",
+			"--email", "licensee@example.com",
+			"--jurisdiction", "texas",
+		}, env)
+		if code != 0 {
+			t.Fatal(stderr.String())
+		}
+		identities, _, err := ReadIdentities(directory)
+		if err != nil || len(identities) != 1 || identities[0].Jurisdiction != "US-TX" {
+			t.Errorf("stored %+v, %v", identities, err)
+		}
+
+		env, _, stderr = newTestEnvironment(directory, "", nil)
+		code = run([]string{
+			"identify",
+			"--name", "Joe Licensee",
+			"--email", "licensee@example.com",
+			"--jurisdiction", "United States",
+		}, env)
+		if code == 0 || !strings.Contains(stderr.String(), "US-TX (Texas, United States)") {
+			t.Errorf("did not list jurisdictions in country:\n%s", stderr.String())
+		}
+	})
+}
+
+func TestHaveReceiptMatchesIdentity(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		license := testLicense()
+		issueTestReceipt(t, directory, license)
+		receipts, _, err := ReadReceipts(directory)
+		if err != nil || len(receipts) != 1 {
+			t.Fatal("could not read receipt")
+		}
+		item := Item{
+			API:     license.Values.API,
+			OfferID: license.Values.OfferID,
+		}
+		if haveReceipt(&item, receipts, nil) {
+			t.Error("counted receipt without identities")
+		}
+		other := Licensee{
+			EMail:        "someone@example.com",
+			Jurisdiction: "US-NY",
+			Name:         "Other Company",
+		}
+		if haveReceipt(&item, receipts, []Licensee{other}) {
+			t.Error("counted receipt for another licensee")
+		}
+		ours := Licensee{
+			EMail:        "Licensee@Example.com",
+			Jurisdiction: "US-TX",
+			Name:         "joe  licensee",
+		}
+		if !haveReceipt(&item, receipts, []Licensee{other, ours}) {
+			t.Error("did not count receipt for our identity")
+		}
+	})
+}
+
+func TestRelicenseReceiptCoversEveryone(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		license := testLicense()
+		license.Values.Relicense = true
+		issueTestReceipt(t, directory, license)
+		receipts, _, err := ReadReceipts(directory)
+		if err != nil || len(receipts) != 1 {
+			t.Fatal("could not read receipt")
+		}
+		if !receipts[0].Relicense() {
+			t.Fatal("did not parse relicense")
+		}
+		if err := receipts[0].ValidateSignature(); err != nil {
+			t.Error("invalid relicense signature")
+		}
+		item := Item{
+			API:     license.Values.API,
+			OfferID: license.Values.OfferID,
+		}
+		if !haveReceipt(&item, receipts, nil) {
+			t.Error("did not count relicense receipt")
+		}
+	})
+}
+
+func TestInventoryWarnsWithoutIdentities(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		issueTestReceipt(t, directory, testLicense())
+		inventory, err := CompileInventory(directory, directory, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inventory.Diagnostics) != 1 || inventory.Diagnostics[0].Cause != errNoIdentities {
+			t.Fatalf("diagnostics: %v", inventory.Diagnostics)
+		}
+
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"quote"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		if !strings.Contains(stderr.String(), "Warning: identity: ") || !strings.Contains(stderr.String(), "licensezero identify") {
+			t.Errorf("stderr:\n%s", stderr.String())
+		}
+
+		identity := testLicense().Values.Licensee
+		if err := writeIdentity(directory, &identity); err != nil {
+			t.Fatal(err)
+		}
+		inventory, err = CompileInventory(directory, directory, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inventory.Diagnostics) != 0 {
+			t.Errorf("diagnostics with an identity: %v", inventory.Diagnostics)
+		}
+	})
+}
+
+func TestIdentifyRemoveRefusesTraversal(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		writeTestFiles(t, directory, map[string]string{
+			"accounts/x.json":  "{}",
+			"identities/.keep": "",
+		})
+		for _, email := range []string{"../accounts/x", "..\\accounts\\x", "..@example.com"} {
+			env, _, stderr := newTestEnvironment(directory, "", nil)
+			if run([]string{"identify", "--remove", email}, env) == 0 {
+				t.Errorf("removed %s", email)
+			}
+			if !strings.Contains(stderr.String(), "invalid e-mail address") {
+				t.Errorf("%s: %s", email, stderr.String())
+			}
+		}
+		if _, err := os.Stat(path.Join(directory, "accounts", "x.json")); err != nil {
+			t.Error("removed a file outside identities")
+		}
+	})
+}
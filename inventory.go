@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path"
 )
@@ -9,10 +11,25 @@ import (
 type Inventory struct {
 	Licensable []Item
 	Licensed   []Item
+	// Legacy are items that would be unlicensed but for receipts
+	// converted from the previous CLI, which can't be verified.
+	Legacy     []Item
 	Own        []Item
 	Unlicensed []Item
 	Ignored    []Item
 	Invalid    []Item
+	// Disallowed are items whose offers the CLI refused to fetch or,
+	// with requireSignedOffers, to trust.
+	Disallowed []DisallowedItem
+	// Diagnostics are problems that kept files or offers out of the
+	// inventory.
+	Diagnostics []Diagnostic
+}
+
+// DisallowedItem is an item naming an API the CLI may not contact.
+type DisallowedItem struct {
+	Item   Item
+	Reason error
 }
 
 // Item describes an artifact with an offer.
@@ -26,6 +43,9 @@ type Item struct {
 	API     string
 	OfferID string
 	Offer   Offer
+	// Unverified explains why the offer's signature could not be
+	// verified, if it couldn't.
+	Unverified error
 }
 
 type finding struct {
@@ -40,27 +60,85 @@ type finding struct {
 }
 
 // CompileInventory discovers artifacts with offers in a working directory.
+// It carries on past unreadable files and offers, recording each problem
+// in Diagnostics.
 func CompileInventory(
 	configPath string,
 	cwd string,
 	ignoreNoncommercial bool,
 	ignoreReciprocal bool,
 ) (inventory *Inventory, err error) {
-	// TODO: Don't ignore receipt read errors.
-	receipts, _, err := ReadReceipts(configPath)
+	inventory = &Inventory{}
+	config, err := readConfigFile(configPath)
 	if err != nil {
 		return
 	}
-	// TODO: Don't ignore account read errors.
-	accounts, _, err := ReadAccounts(configPath)
-	findings, err := find(cwd)
+	record := func(source string, errs ...error) {
+		for _, err := range errs {
+			inventory.Diagnostics = append(inventory.Diagnostics, diagnose(source, err))
+		}
+	}
+	receipts, receiptErrors, err := ReadReceipts(configPath)
+	record(diagnosticReceipt, receiptErrors...)
 	if err != nil {
-		return
+		record(diagnosticReceipt, err)
+	}
+	legacy, legacyErrors, err := ReadLegacyReceipts(configPath)
+	record(diagnosticReceipt, legacyErrors...)
+	if err != nil {
+		record(diagnosticReceipt, err)
+	}
+	accounts, accountErrors, err := ReadAccounts(configPath)
+	record(diagnosticAccount, accountErrors...)
+	if err != nil {
+		record(diagnosticAccount, err)
 	}
+	identities, identityErrors, err := ReadIdentities(configPath)
+	record(diagnosticIdentity, identityErrors...)
+	if err != nil {
+		record(diagnosticIdentity, err)
+	}
+	if len(identities) == 0 && needIdentity(receipts) {
+		inventory.Diagnostics = append(inventory.Diagnostics, Diagnostic{
+			Path:   path.Join(configPath, "identities"),
+			Source: diagnosticIdentity,
+			Cause:  errNoIdentities,
+		})
+	}
+	findings, findDiagnostics := find(cwd)
+	inventory.Diagnostics = append(inventory.Diagnostics, findDiagnostics...)
+	offers, offerErrors := fetchOffers(findings, config)
 	for _, finding := range findings {
-		offer, err := GetOffer(finding.API, finding.OfferID)
+		if err := config.checkAPIAllowed(finding.API); err != nil {
+			inventory.Disallowed = append(inventory.Disallowed, DisallowedItem{
+				Item: Item{
+					Type:    finding.Type,
+					Path:    finding.Path,
+					Scope:   finding.Scope,
+					Name:    finding.Name,
+					Version: finding.Version,
+					Public:  finding.Public,
+					API:     finding.API,
+					OfferID: finding.OfferID,
+				},
+				Reason: err,
+			})
+			continue
+		}
+		key := offerKey{finding.API, finding.OfferID}
+		offer, err := offers[key], offerErrors[key]
 		var item Item
 		if err != nil {
+			source := diagnosticNetwork
+			var invalid *invalidOfferError
+			if errors.As(err, &invalid) {
+				source = diagnosticParser
+			}
+			inventory.Diagnostics = append(inventory.Diagnostics, Diagnostic{
+				Path:   finding.Path,
+				Source: source,
+				Cause:  fmt.Errorf("offer %s from %s: %v", finding.OfferID, finding.API, err),
+			})
 			inventory.Invalid = append(inventory.Invalid, Item{
 				Type:    finding.Type,
 				Path:    finding.Path,
@@ -68,6 +146,8 @@ func CompileInventory(
 				Name:    finding.Name,
 				Version: finding.Version,
 				Public:  finding.Public,
+				API:     finding.API,
+				OfferID: finding.OfferID,
 			})
 			continue
 		} else {
@@ -78,11 +158,21 @@ func CompileInventory(
 				Name:    finding.Name,
 				Version: finding.Version,
 				Public:  finding.Public,
+				API:     finding.API,
+				OfferID: finding.OfferID,
 				Offer:   offer,
 			}
+			item.Unverified = config.verifyOffer(finding.API, finding.OfferID, &offer)
+			if item.Unverified != nil && config.RequireSignedOffers {
+				inventory.Disallowed = append(inventory.Disallowed, DisallowedItem{
+					Item:   item,
+					Reason: item.Unverified,
+				})
+				continue
+			}
 			inventory.Licensable = append(inventory.Licensable, item)
 		}
-		if haveReceipt(&item, receipts) {
+		if haveReceipt(&item, receipts, identities) {
 			inventory.Licensed = append(inventory.Licensed, item)
 			continue
 		}
@@ -99,13 +189,64 @@ func CompileInventory(
 			inventory.Ignored = append(inventory.Ignored, item)
 			continue
 		}
+		if haveLegacyReceipt(&item, legacy) {
+			inventory.Legacy = append(inventory.Legacy, item)
+			continue
+		}
 		inventory.Unlicensed = append(inventory.Unlicensed, item)
 	}
+	return inventory, nil
+}
+
+var errNoIdentities = errors.New("no identities configured, so receipts for licensees don't count; configure one with licensezero identify")
+
+// needIdentity reports whether any receipts count only for a
+// configured identity.
+func needIdentity(receipts []Receipt) bool {
+	for _, receipt := range receipts {
+		if !receipt.Relicense() {
+			return true
+		}
+	}
+	return false
+}
+
+// offerKey identifies an offer.
+type offerKey struct {
+	API     string
+	OfferID string
+}
+
+// fetchOffers gets the offers for findings from allowed APIs, grouping
+// requests by API.
+func fetchOffers(findings []finding, config *configFile) (offers map[offerKey]Offer, errs map[offerKey]error) {
+	offers = make(map[offerKey]Offer)
+	errs = make(map[offerKey]error)
+	var apis []string
+	offerIDs := make(map[string][]string)
+	for _, finding := range findings {
+		if config.checkAPIAllowed(finding.API) != nil {
+			continue
+		}
+		if _, ok := offerIDs[finding.API]; !ok {
+			apis = append(apis, finding.API)
+		}
+		offerIDs[finding.API] = append(offerIDs[finding.API], finding.OfferID)
+	}
+	for _, api := range apis {
+		found, failed := GetOffers(api, offerIDs[api])
+		for offerID, offer := range found {
+			offers[offerKey{api, offerID}] = offer
+		}
+		for offerID, err := range failed {
+			errs[offerKey{api, offerID}] = err
+		}
+	}
 	return
 }
 
-func find(cwd string) (findings []finding, err error) {
-	finders := []func(string) ([]finding, error){
+func find(cwd string) (findings []finding, diagnostics []Diagnostic) {
+	finders := []func(string) ([]finding, []Diagnostic){
 		// findNPMPackages,
 		// findRubyGems,
 		// findGoDeps,
@@ -113,14 +254,13 @@ func find(cwd string) (findings []finding, err error) {
 		findLicenseZeroFiles,
 	}
 	for _, finder := range finders {
-		findings, err := finder(cwd)
-		if err == nil {
-			for _, finding := range findings {
-				if alreadyHave(findings, &finding) {
-					continue
-				}
-				findings = append(findings, finding)
+		found, problems := finder(cwd)
+		diagnostics = append(diagnostics, problems...)
+		for _, finding := range found {
+			if alreadyHave(findings, &finding) {
+				continue
 			}
+			findings = append(findings, finding)
 		}
 	}
 	return
@@ -137,11 +277,29 @@ func alreadyHave(findings []finding, finding *finding) bool {
 	return false
 }
 
-func haveReceipt(item *Item, receipts []Receipt) bool {
+// haveReceipt reports whether there is a receipt for an item that
+// covers one of the configured identities. Relicense receipts cover
+// everyone.
+func haveReceipt(item *Item, receipts []Receipt, identities []Licensee) bool {
 	api := item.API
 	offerID := item.OfferID
-	for _, account := range receipts {
-		if account.API() == api && account.OfferID() == offerID {
+	for _, receipt := range receipts {
+		if receipt.API() != api || receipt.OfferID() != offerID {
+			continue
+		}
+		if receipt.Relicense() || matchesIdentity(receipt.Licensee(), identities) {
+			return true
+		}
+	}
+	return false
+}
+
+// haveLegacyReceipt reports whether there is a legacy receipt for an
+// item. Legacy receipts name licensees the old way, so any receipt for
+// the offer counts.
+func haveLegacyReceipt(item *Item, receipts []Receipt) bool {
+	for _, receipt := range receipts {
+		if receipt.API() == item.API && receipt.OfferID() == item.OfferID {
 			return true
 		}
 	}
@@ -159,38 +317,53 @@ func ownProject(item *Item, accounts []Account) bool {
 	return false
 }
 
-// Like ioutil.ReadDir, but don't sort, and read all symlinks.
-func readAndStatDir(directoryPath string) ([]os.FileInfo, error) {
+// Like ioutil.ReadDir, but don't sort, and read all symlinks. Symlinks
+// that can't be followed are left out, with an error for each.
+func readAndStatDir(directoryPath string) (entries []os.FileInfo, linkErrors []error, err error) {
 	directory, err := os.Open(directoryPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	entries, err := directory.Readdir(-1)
+	read, err := directory.Readdir(-1)
 	directory.Close()
 	if err != nil {
-		return nil, err
-	}
-	returned := make([]os.FileInfo, len(entries))
-	for i, entry := range entries {
-		if isSymlink(entry) {
-			linkPath := path.Join(directoryPath, entry.Name())
-			targetPath, err := os.Readlink(linkPath)
-			if err != nil {
-				return nil, err
-			}
-			if !path.IsAbs(targetPath) {
-				targetPath = path.Join(path.Dir(directoryPath), targetPath)
-			}
-			newEntry, err := os.Stat(targetPath)
-			if err != nil {
-				return nil, err
-			}
-			returned[i] = newEntry
-		} else {
-			returned[i] = entry
+		return nil, nil, err
+	}
+	for _, entry := range read {
+		if !isSymlink(entry) {
+			entries = append(entries, entry)
+			continue
+		}
+		linkPath := path.Join(directoryPath, entry.Name())
+		targetPath, err := os.Readlink(linkPath)
+		if err != nil {
+			linkErrors = append(linkErrors, &fileError{linkPath, err})
+			continue
 		}
+		if !path.IsAbs(targetPath) {
+			targetPath = path.Join(directoryPath, targetPath)
+		}
+		// Stat the link, rather than its target, to keep the link's name.
+		target, err := os.Stat(linkPath)
+		if err != nil {
+			linkErrors = append(linkErrors, &fileError{
+				linkPath,
+				fmt.Errorf("broken symbolic link to %s: %v", targetPath, unwrapPathError(err)),
+			})
+			continue
+		}
+		entries = append(entries, target)
+	}
+	return entries, linkErrors, nil
+}
+
+// unwrapPathError drops the path from an *os.PathError, for messages
+// that already name the file.
+func unwrapPathError(err error) error {
+	if pathError, ok := err.(*os.PathError); ok {
+		return pathError.Err
 	}
-	return returned, nil
+	return err
 }
 
 func isSymlink(entry os.FileInfo) bool {
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// diagnosticSources maps the paths of an inventory's diagnostics to
+// their sources.
+func diagnosticSources(inventory *Inventory) map[string]string {
+	sources := make(map[string]string)
+	for _, diagnostic := range inventory.Diagnostics {
+		sources[diagnostic.Path] = diagnostic.Source
+	}
+	return sources
+}
+
+func TestInventoryDiagnostics(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		project := path.Join(directory, "project")
+		writeTestFiles(t, directory, map[string]string{
+			"project/node_modules/broken/licensezero.json": "{",
+			"receipts/broken.json":                         "not JSON",
+		})
+		err := writeTestArtifact(path.Join(project, "node_modules", "dependency"), "https://internal.example.com", "9aab7058-599a-43db-9449-5fc0971ecbfa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		inventory, err := CompileInventory(directory, project, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inventory.Disallowed) != 1 {
+			t.Errorf("stopped scanning: disallowed %d", len(inventory.Disallowed))
+		}
+		found := diagnosticSources(inventory)
+		for filePath, source := range map[string]string{
+			path.Join(project, "node_modules", "broken", "licensezero.json"): diagnosticParser,
+			path.Join(directory, "receipts", "broken.json"):                  diagnosticReceipt,
+		} {
+			if found[filePath] != source {
+				t.Errorf("no %s diagnostic for %s in %v", source, filePath, inventory.Diagnostics)
+			}
+		}
+		if len(inventory.Diagnostics) != 2 {
+			t.Errorf("diagnostics: %v", inventory.Diagnostics)
+		}
+	})
+}
+
+func TestInventoryDanglingSymlink(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		project := path.Join(directory, "project")
+		modules := path.Join(project, "node_modules")
+		err := writeTestArtifact(path.Join(modules, "dependency"), "https://internal.example.com", "9aab7058-599a-43db-9449-5fc0971ecbfa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writeTestArtifact(path.Join(directory, "linked"), "https://internal.example.com", "d56ee0a6-4ed3-4793-9485-6135644c158f")
+		if err != nil {
+			t.Fatal(err)
+		}
+		broken := path.Join(modules, "broken")
+		if err := os.Symlink(path.Join(directory, "nonexistent"), broken); err != nil {
+			t.Fatal(err)
+		}
+		// Relative targets resolve from the directory holding the link.
+		if err := os.Symlink(path.Join("..", "..", "linked"), path.Join(modules, "relative")); err != nil {
+			t.Fatal(err)
+		}
+		inventory, err := CompileInventory(directory, project, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inventory.Disallowed) != 2 {
+			t.Errorf("found %d of 2 artifacts", len(inventory.Disallowed))
+		}
+		if source := diagnosticSources(inventory)[broken]; source != diagnosticFinder {
+			t.Errorf("no finder diagnostic for %s in %v", broken, inventory.Diagnostics)
+		}
+		if len(inventory.Diagnostics) != 1 {
+			t.Errorf("diagnostics: %v", inventory.Diagnostics)
+		}
+	})
+}
+
+func TestInventorySymlinkLoop(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		project := path.Join(directory, "project")
+		dependency := path.Join(project, "node_modules", "a")
+		err := writeTestArtifact(dependency, "https://internal.example.com", "9aab7058-599a-43db-9449-5fc0971ecbfa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"up", "up2"} {
+			if err := os.Symlink("..", path.Join(dependency, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		done := make(chan *Inventory)
+		go func() {
+			inventory, err := CompileInventory(directory, project, false, false)
+			if err != nil {
+				t.Error(err)
+			}
+			done <- inventory
+		}()
+		select {
+		case inventory := <-done:
+			if inventory == nil {
+				return
+			}
+			if len(inventory.Disallowed) != 1 {
+				t.Errorf("found %d artifacts", len(inventory.Disallowed))
+			}
+			if len(inventory.Diagnostics) != 0 {
+				t.Errorf("diagnostics: %v", inventory.Diagnostics)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("scan did not finish")
+		}
+	})
+}
+
+func TestInventoryUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	WithTestDir(t, func(directory string) {
+		project := path.Join(directory, "project")
+		err := writeTestArtifact(path.Join(project, "node_modules", "dependency"), "https://internal.example.com", "9aab7058-599a-43db-9449-5fc0971ecbfa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		unreadable := path.Join(project, "node_modules", "unreadable")
+		if err := os.Mkdir(unreadable, 0); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chmod(unreadable, 0755)
+		inventory, err := CompileInventory(directory, project, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inventory.Disallowed) != 1 {
+			t.Errorf("stopped scanning: disallowed %d", len(inventory.Disallowed))
+		}
+		if source := diagnosticSources(inventory)[unreadable]; source != diagnosticFinder {
+			t.Errorf("no finder diagnostic for %s in %v", unreadable, inventory.Diagnostics)
+		}
+	})
+}
+
+func TestInventoryMissingDirectory(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		inventory, err := CompileInventory(directory, path.Join(directory, "missing"), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inventory.Diagnostics) != 0 {
+			t.Errorf("diagnostics: %v", inventory.Diagnostics)
+		}
+	})
+}
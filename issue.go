@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"io/ioutil"
+	"time"
+)
+
+// signV1Receipt signs a license and returns the resulting receipt.
+func signV1Receipt(license license1_0_0Pre, privateKey ed25519.PrivateKey) (*receipt1_0_0Pre, error) {
+	serialized, err := canonicalJSON(license)
+	if err != nil {
+		return nil, err
+	}
+	signature := ed25519.Sign(privateKey, serialized)
+	return &receipt1_0_0Pre{
+		Key:       hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+		Signature: hex.EncodeToString(signature),
+		License:   license,
+	}, nil
+}
+
+// checkV1ReceiptSchema validates a receipt against the receipt schema
+// and returns a description of each problem.
+func checkV1ReceiptSchema(receipt *receipt1_0_0Pre) (problems []string, err error) {
+	generic, err := toGenericJSON(receipt)
+	if err != nil {
+		return nil, err
+	}
+	result, err := validateV1Receipt(generic)
+	if err != nil {
+		return nil, err
+	}
+	for _, resultError := range result.Errors() {
+		problems = append(problems, resultError.String())
+	}
+	return
+}
+
+const issueUsage = `Issue a signed receipt.
+
+Usage:
+  licensezero issue [flags]
+
+Flags:
+  --key NAME                     signing key (default "default")
+  --template FILE                JSON license template, with form and values
+  --form FILE                    license form text
+  --api URL                      licensing API
+  --offer ID                     offer identifier
+  --order ID                     order identifier (default random)
+  --effective TIME               effective date (default now)
+  --expires TIME                 expiration date
+  --price PRICE                  purchase price, like 1000USD
+  --relicense                    issue a relicense, covering everyone
+  --licensee-name NAME
+  --licensee-email EMAIL
+  --licensee-jurisdiction CODE
+  --licensor-name NAME
+  --licensor-email EMAIL
+  --licensor-jurisdiction CODE
+  --licensor-id ID
+  --vendor-name NAME
+  --vendor-email EMAIL
+  --vendor-jurisdiction CODE
+  --vendor-website URL
+  --output FILE                  write the receipt to a file
+
+Flags override values from the template.
+`
+
+var issueCommand = subcommand{
+	Summary: "Issue a signed receipt.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("issue", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, issueUsage) }
+		keyName := flagSet.String("key", "default", "")
+		template := flagSet.String("template", "", "")
+		form := flagSet.String("form", "", "")
+		api := flagSet.String("api", "", "")
+		offerID := flagSet.String("offer", "", "")
+		orderID := flagSet.String("order", "", "")
+		effective := flagSet.String("effective", "", "")
+		expires := flagSet.String("expires", "", "")
+		price := flagSet.String("price", "", "")
+		relicense := flagSet.Bool("relicense", false, "")
+		licenseeName := flagSet.String("licensee-name", "", "")
+		licenseeEMail := flagSet.String("licensee-email", "", "")
+		licenseeJurisdiction := flagSet.String("licensee-jurisdiction", "", "")
+		licensorName := flagSet.String("licensor-name", "", "")
+		licensorEMail := flagSet.String("licensor-email", "", "")
+		licensorJurisdiction := flagSet.String("licensor-jurisdiction", "", "")
+		licensorID := flagSet.String("licensor-id", "", "")
+		vendorName := flagSet.String("vendor-name", "", "")
+		vendorEMail := flagSet.String("vendor-email", "", "")
+		vendorJurisdiction := flagSet.String("vendor-jurisdiction", "", "")
+		vendorWebsite := flagSet.String("vendor-website", "", "")
+		output := flagSet.String("output", "", "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 {
+			flagSet.Usage()
+			return 1
+		}
+
+		var license license1_0_0Pre
+		if *template != "" {
+			data, err := ioutil.ReadFile(*template)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not read template:", err)
+				return 1
+			}
+			err = json.Unmarshal(data, &license)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Invalid template:", err)
+				return 1
+			}
+		}
+		if *form != "" {
+			data, err := ioutil.ReadFile(*form)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not read form:", err)
+				return 1
+			}
+			license.Form = string(data)
+		}
+
+		values := &license.Values
+		override := func(target *string, value string) {
+			if value != "" {
+				*target = value
+			}
+		}
+		override(&values.API, *api)
+		override(&values.OfferID, *offerID)
+		override(&values.OrderID, *orderID)
+		override(&values.Effective, *effective)
+		override(&values.Expires, *expires)
+		override(&values.Licensee.Name, *licenseeName)
+		override(&values.Licensee.EMail, *licenseeEMail)
+		override(&values.Licensee.Jurisdiction, *licenseeJurisdiction)
+		override(&values.Licensor.Name, *licensorName)
+		override(&values.Licensor.EMail, *licensorEMail)
+		override(&values.Licensor.Jurisdiction, *licensorJurisdiction)
+		override(&values.Licensor.LicensorID, *licensorID)
+		if *price != "" {
+			parsed, err := parsePrice(*price)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, err)
+				return 1
+			}
+			values.Price = &parsed
+		}
+		if *relicense {
+			values.Relicense = true
+		}
+		if *vendorName != "" || *vendorEMail != "" || *vendorJurisdiction != "" || *vendorWebsite != "" {
+			if values.Vendor == nil {
+				values.Vendor = &Vendor{}
+			}
+			override(&values.Vendor.Name, *vendorName)
+			override(&values.Vendor.EMail, *vendorEMail)
+			override(&values.Vendor.Jurisdiction, *vendorJurisdiction)
+			override(&values.Vendor.Website, *vendorWebsite)
+		}
+		if values.OrderID == "" {
+			generated, err := newUUID()
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not generate order ID:", err)
+				return 1
+			}
+			values.OrderID = generated
+		}
+		if values.Effective == "" {
+			values.Effective = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		passphrase, err := readPassphrase(env, "Passphrase")
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read passphrase:", err)
+			return 1
+		}
+		privateKey, err := readSigningKey(env.Config, *keyName, passphrase)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read signing key:", err)
+			return 1
+		}
+		receipt, err := signV1Receipt(license, privateKey)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not sign receipt:", err)
+			return 1
+		}
+		problems, err := checkV1ReceiptSchema(receipt)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not validate receipt:", err)
+			return 1
+		}
+		if len(problems) != 0 {
+			fmt.Fprintln(env.Stderr, "Invalid receipt:")
+			for _, problem := range problems {
+				fmt.Fprintln(env.Stderr, "- "+problem)
+			}
+			return 1
+		}
+		err = writeReceiptJSON(env, receipt, *output)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not write receipt:", err)
+			return 1
+		}
+		return 0
+	},
+}
+
+func writeReceiptJSON(env *environment, receipt *receipt1_0_0Pre, output string) error {
+	data, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if output == "" {
+		_, err = env.Stdout.Write(data)
+		return err
+	}
+	if _, err := ioutil.ReadFile(output); err == nil {
+		return errors.New(output + " already exists")
+	}
+	return ioutil.WriteFile(output, data, 0644)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"golang.org/x/crypto/ed25519"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestIssue(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		variables := map[string]string{passphraseEnvironmentVariable: "test passphrase"}
+		env, stdout, stderr := newTestEnvironment(directory, "", variables)
+		if run([]string{"keygen"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		publicKey := strings.TrimSpace(stdout.String())
+
+		template := path.Join(directory, "template.json")
+		err := ioutil.WriteFile(template, []byte(`{
+  "form": "Test license form.",
+  "values": {
+    "api": "https://api.licensezero.com",
+    "offerID": "9aab7058-599a-43db-9449-5fc0971ecbfa",
+    "licensor": {
+      "email": "licensor@example.com",
+      "jurisdiction": "US-CA",
+      "name": "Jane Licensor",
+      "licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664"
+    }
+  }
+}`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		env, stdout, stderr = newTestEnvironment(directory, "", variables)
+		code := run([]string{
+			"issue",
+			"--template", template,
+			"--licensee-name", "Jürgen Groß",
+			"--licensee-email", "juergen@example.de",
+			"--licensee-jurisdiction", "DE-BE",
+			"--price", "1000EUR",
+		}, env)
+		if code != 0 {
+			t.Fatal(stderr.String())
+		}
+		var unstructured interface{}
+		err = json.Unmarshal(stdout.Bytes(), &unstructured)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipt, err := ParseReceipt(unstructured)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := receipt.ValidateSignature(); err != nil {
+			t.Error("invalid signature")
+		}
+		if receipt.Licensee().Name != "Jürgen Groß" {
+			t.Error("did not set licensee name")
+		}
+		if receipt.Price().Currency != "EUR" {
+			t.Error("did not set price")
+		}
+		if receipt.OrderID() == "" || receipt.Effective() == "" {
+			t.Error("did not set default order ID and effective date")
+		}
+		if receipt.(receipt1_0_0Pre).Key != publicKey {
+			t.Error("signed with wrong key")
+		}
+	})
+}
+
+func TestIssueInvalid(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		variables := map[string]string{passphraseEnvironmentVariable: "test passphrase"}
+		env, _, stderr := newTestEnvironment(directory, "", variables)
+		if run([]string{"keygen"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		env, _, stderr = newTestEnvironment(directory, "", variables)
+		if run([]string{"issue", "--licensee-name", "Joe Licensee"}, env) == 0 {
+			t.Error("issued invalid receipt")
+		}
+		if !strings.Contains(stderr.String(), "Invalid receipt") {
+			t.Error("did not report schema problems")
+		}
+	})
+}
+
+func TestIssueWrongPassphrase(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		env, _, stderr := newTestEnvironment(directory, "", map[string]string{
+			passphraseEnvironmentVariable: "right",
+		})
+		if run([]string{"keygen"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		env, _, stderr = newTestEnvironment(directory, "wrong\n", nil)
+		if run([]string{"issue"}, env) == 0 {
+			t.Error("opened key with wrong passphrase")
+		}
+		if !strings.Contains(stderr.String(), "wrong passphrase") {
+			t.Error("did not report wrong passphrase")
+		}
+	})
+}
+
+// issueTestReceipt signs a license with a new key and writes the
+// receipt to the receipts directory.
+func issueTestReceipt(t *testing.T, directory string, license license1_0_0Pre) *receipt1_0_0Pre {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := signV1Receipt(license, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts := path.Join(directory, "receipts")
+	err = os.MkdirAll(receipts, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(receiptPath(directory, receipt), data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return receipt
+}
+
+func testLicense() license1_0_0Pre {
+	var license license1_0_0Pre
+	license.Form = "Test license form.\n\nSecond paragraph."
+	license.Values.API = "https://api.licensezero.com"
+	license.Values.OfferID = "9aab7058-599a-43db-9449-5fc0971ecbfa"
+	license.Values.OrderID = "2c743a84-09ce-4549-9f0d-19d8f53462bb"
+	license.Values.Effective = "2018-11-13T20:20:39Z"
+	license.Values.Licensee = Licensee{
+		EMail:        "licensee@example.com",
+		Jurisdiction: "US-TX",
+		Name:         "Joe Licensee",
+	}
+	license.Values.Licensor = Licensor{
+		EMail:        "licensor@example.com",
+		Jurisdiction: "US-CA",
+		LicensorID:   "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+		Name:         "Jane Licensor",
+	}
+	return license
+}
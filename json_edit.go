@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// jsonMember locates a member of a JSON object within a document.
+type jsonMember struct {
+	Key        string
+	KeyStart   int
+	ValueStart int
+	ValueEnd   int
+}
+
+// jsonObject locates an object and its members within a document.
+type jsonObject struct {
+	Start   int
+	End     int
+	Members []jsonMember
+}
+
+// jsonArray locates an array and its elements within a document.
+type jsonArray struct {
+	Start    int
+	End      int
+	Elements []jsonElement
+}
+
+// jsonElement locates an element of a JSON array within a document.
+type jsonElement struct {
+	Start int
+	End   int
+}
+
+var errInvalidJSON = errors.New("invalid JSON")
+
+// locateObject scans the JSON object starting at offset, recording
+// where each of its members appears, without decoding values.
+func locateObject(data []byte, offset int) (object jsonObject, err error) {
+	index := skipWhitespace(data, offset)
+	if index >= len(data) || data[index] != '{' {
+		return object, errors.New("not a JSON object")
+	}
+	object.Start = index
+	index = skipWhitespace(data, index+1)
+	if index < len(data) && data[index] == '}' {
+		object.End = index + 1
+		return
+	}
+	for index < len(data) {
+		var member jsonMember
+		member.KeyStart = index
+		keyEnd, err := skipValue(data, index)
+		if err != nil || data[index] != '"' {
+			return object, errInvalidJSON
+		}
+		err = json.Unmarshal(data[index:keyEnd], &member.Key)
+		if err != nil {
+			return object, err
+		}
+		index = skipWhitespace(data, keyEnd)
+		if index >= len(data) || data[index] != ':' {
+			return object, errInvalidJSON
+		}
+		member.ValueStart = skipWhitespace(data, index+1)
+		member.ValueEnd, err = skipValue(data, member.ValueStart)
+		if err != nil {
+			return object, err
+		}
+		object.Members = append(object.Members, member)
+		index = skipWhitespace(data, member.ValueEnd)
+		if index >= len(data) {
+			break
+		}
+		if data[index] == '}' {
+			object.End = index + 1
+			return object, nil
+		}
+		if data[index] != ',' {
+			return object, errInvalidJSON
+		}
+		index = skipWhitespace(data, index+1)
+	}
+	return object, errInvalidJSON
+}
+
+// locateArray scans the JSON array starting at offset, recording where
+// each of its elements appears, without decoding them.
+func locateArray(data []byte, offset int) (array jsonArray, err error) {
+	index := skipWhitespace(data, offset)
+	if index >= len(data) || data[index] != '[' {
+		return array, errors.New("not a JSON array")
+	}
+	array.Start = index
+	index = skipWhitespace(data, index+1)
+	if index < len(data) && data[index] == ']' {
+		array.End = index + 1
+		return
+	}
+	for index < len(data) {
+		var element jsonElement
+		element.Start = index
+		element.End, err = skipValue(data, index)
+		if err != nil {
+			return array, err
+		}
+		array.Elements = append(array.Elements, element)
+		index = skipWhitespace(data, element.End)
+		if index >= len(data) {
+			break
+		}
+		if data[index] == ']' {
+			array.End = index + 1
+			return array, nil
+		}
+		if data[index] != ',' {
+			return array, errInvalidJSON
+		}
+		index = skipWhitespace(data, index+1)
+	}
+	return array, errInvalidJSON
+}
+
+// multiline reports whether an array's elements start on their own
+// lines.
+func (array *jsonArray) multiline(data []byte) bool {
+	if len(array.Elements) == 0 {
+		return true
+	}
+	return bytes.IndexByte(data[array.Start:array.Elements[0].Start], '\n') != -1
+}
+
+// appendElement adds an element at the end of an array, following the
+// formatting of the elements already there.
+func appendElement(data []byte, array *jsonArray, unit string, value interface{}) ([]byte, error) {
+	var result bytes.Buffer
+	if len(array.Elements) == 0 {
+		indentation := string(lineIndentation(data, array.Start))
+		encoded, err := marshalIndent(value, indentation+unit, unit)
+		if err != nil {
+			return nil, err
+		}
+		result.Write(data[:array.Start])
+		result.WriteString("[\n" + indentation + unit)
+		result.Write(encoded)
+		result.WriteString("\n" + indentation + "]")
+		result.Write(data[array.End:])
+		return result.Bytes(), nil
+	}
+	last := array.Elements[len(array.Elements)-1]
+	var separator string
+	var encoded []byte
+	var err error
+	if array.multiline(data) {
+		indentation := string(lineIndentation(data, last.Start))
+		separator = ",\n" + indentation
+		encoded, err = marshalIndent(value, indentation, unit)
+	} else {
+		separator = ", "
+		encoded, err = marshalCompact(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Write(data[:last.End])
+	result.WriteString(separator)
+	result.Write(encoded)
+	result.Write(data[last.End:])
+	return result.Bytes(), nil
+}
+
+// replaceElement replaces an element of an array in place.
+func replaceElement(data []byte, array *jsonArray, index int, unit string, value interface{}) ([]byte, error) {
+	element := array.Elements[index]
+	var encoded []byte
+	var err error
+	if array.multiline(data) {
+		encoded, err = marshalIndent(value, string(lineIndentation(data, element.Start)), unit)
+	} else {
+		encoded, err = marshalCompact(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result bytes.Buffer
+	result.Write(data[:element.Start])
+	result.Write(encoded)
+	result.Write(data[element.End:])
+	return result.Bytes(), nil
+}
+
+// removeElement removes an element of an array, along with the
+// separator between it and its neighbor.
+func removeElement(data []byte, array *jsonArray, index int) []byte {
+	var start, end int
+	elements := array.Elements
+	if len(elements) == 1 {
+		start, end = array.Start+1, array.End-1
+	} else if index < len(elements)-1 {
+		start, end = elements[index].Start, elements[index+1].Start
+	} else {
+		start, end = elements[index-1].End, elements[index].End
+	}
+	var result bytes.Buffer
+	result.Write(data[:start])
+	result.Write(data[end:])
+	return result.Bytes()
+}
+
+// member returns the member of an object with a key, if any.
+func (object *jsonObject) member(key string) *jsonMember {
+	for index := range object.Members {
+		if object.Members[index].Key == key {
+			return &object.Members[index]
+		}
+	}
+	return nil
+}
+
+func skipWhitespace(data []byte, index int) int {
+	for index < len(data) {
+		switch data[index] {
+		case ' ', '\t', '\n', '\r':
+			index++
+		default:
+			return index
+		}
+	}
+	return index
+}
+
+// skipValue returns the offset just past the JSON value at index.
+func skipValue(data []byte, index int) (int, error) {
+	if index >= len(data) {
+		return index, errInvalidJSON
+	}
+	switch data[index] {
+	case '"':
+		for index++; index < len(data); index++ {
+			switch data[index] {
+			case '\\':
+				index++
+			case '"':
+				return index + 1, nil
+			}
+		}
+		return index, errInvalidJSON
+	case '{', '[':
+		depth := 0
+		for ; index < len(data); index++ {
+			switch data[index] {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return index + 1, nil
+				}
+			case '"':
+				end, err := skipValue(data, index)
+				if err != nil {
+					return end, err
+				}
+				index = end - 1
+			}
+		}
+		return index, errInvalidJSON
+	default:
+		start := index
+		for index < len(data) {
+			switch data[index] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				if index == start {
+					return index, errInvalidJSON
+				}
+				return index, nil
+			}
+			index++
+		}
+		return index, nil
+	}
+}
+
+// lineIndentation returns the whitespace at the start of the line
+// containing offset.
+func lineIndentation(data []byte, offset int) []byte {
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	end := lineStart
+	for end < offset && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	return data[lineStart:end]
+}
+
+// indentationUnit guesses the indentation a document uses per level,
+// defaulting to two spaces.
+func indentationUnit(data []byte, object *jsonObject) string {
+	if len(object.Members) != 0 {
+		first := object.Members[0]
+		memberIndentation := lineIndentation(data, first.KeyStart)
+		objectIndentation := lineIndentation(data, object.Start)
+		if len(memberIndentation) > len(objectIndentation) {
+			return string(memberIndentation[len(objectIndentation):])
+		}
+	}
+	return "  "
+}
+
+// setMember sets the value of a member of the object, replacing its
+// existing value in place or adding it as the object's last member,
+// and leaves the rest of the document as it was.
+func setMember(data []byte, object *jsonObject, key string, value interface{}) ([]byte, error) {
+	unit := indentationUnit(data, object)
+	objectIndentation := string(lineIndentation(data, object.Start))
+	var result bytes.Buffer
+	if existing := object.member(key); existing != nil {
+		prefix := string(lineIndentation(data, existing.KeyStart))
+		encoded, err := marshalIndent(value, prefix, unit)
+		if err != nil {
+			return nil, err
+		}
+		result.Write(data[:existing.ValueStart])
+		result.Write(encoded)
+		result.Write(data[existing.ValueEnd:])
+		return result.Bytes(), nil
+	}
+	prefix := objectIndentation + unit
+	encoded, err := marshalIndent(value, prefix, unit)
+	if err != nil {
+		return nil, err
+	}
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	closing := object.End - 1
+	if len(object.Members) == 0 {
+		result.Write(data[:object.Start+1])
+		result.WriteString("\n" + prefix)
+		result.Write(encodedKey)
+		result.WriteString(": ")
+		result.Write(encoded)
+		result.WriteString("\n" + objectIndentation)
+	} else {
+		last := object.Members[len(object.Members)-1]
+		result.Write(data[:last.ValueEnd])
+		result.WriteString(",\n" + prefix)
+		result.Write(encodedKey)
+		result.WriteString(": ")
+		result.Write(encoded)
+		result.Write(data[last.ValueEnd:closing])
+	}
+	result.Write(data[closing:])
+	return result.Bytes(), nil
+}
+
+// marshalCompact is json.Marshal without escaping HTML.
+func marshalCompact(value interface{}) ([]byte, error) {
+	return marshalIndent(value, "", "")
+}
+
+// marshalIndent is json.MarshalIndent without escaping HTML.
+func marshalIndent(value interface{}, prefix string, indent string) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(prefix, indent)
+	err := encoder.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
@@ -0,0 +1,242 @@
+// Package jurisdiction describes the ISO 3166-2 jurisdictions that
+// licensors, licensees, and vendors can be in.
+package jurisdiction
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Jurisdiction is a subdivision of a country.
+type Jurisdiction struct {
+	// Code is the ISO 3166-2 code, like "US-CA".
+	Code string
+	// Name is the subdivision's name, if known, like "California".
+	Name string
+}
+
+// Country is a country with jurisdictions.
+type Country struct {
+	// Code is the ISO 3166-1 alpha-2 code, like "US".
+	Code string
+	Name string
+}
+
+var (
+	byCode        = make(map[string]Jurisdiction, len(jurisdictions))
+	byName        = make(map[string][]Jurisdiction)
+	countryByCode = make(map[string]Country, len(countries))
+	countryByName = make(map[string]Country, len(countries))
+	inCountry     = make(map[string][]Jurisdiction, len(countries))
+)
+
+func init() {
+	for _, jurisdiction := range jurisdictions {
+		byCode[jurisdiction.Code] = jurisdiction
+		if jurisdiction.Name != "" {
+			folded := fold(jurisdiction.Name)
+			byName[folded] = append(byName[folded], jurisdiction)
+		}
+		country := jurisdiction.countryCode()
+		inCountry[country] = append(inCountry[country], jurisdiction)
+	}
+	for _, country := range countries {
+		countryByCode[country.Code] = country
+		countryByName[fold(country.Name)] = country
+	}
+}
+
+// All returns every jurisdiction, sorted by code.
+func All() []Jurisdiction {
+	return append([]Jurisdiction(nil), jurisdictions...)
+}
+
+// Codes returns the code of every jurisdiction, sorted.
+func Codes() []string {
+	codes := make([]string, len(jurisdictions))
+	for index, jurisdiction := range jurisdictions {
+		codes[index] = jurisdiction.Code
+	}
+	return codes
+}
+
+// ByCode finds a jurisdiction by code, ignoring case.
+func ByCode(code string) (Jurisdiction, bool) {
+	jurisdiction, ok := byCode[strings.ToUpper(strings.TrimSpace(code))]
+	return jurisdiction, ok
+}
+
+// ByName finds jurisdictions by name, ignoring case and accents.
+// Jurisdictions in different countries may share a name.
+func ByName(name string) []Jurisdiction {
+	return append([]Jurisdiction(nil), byName[fold(name)]...)
+}
+
+// FindCountry finds a country by code or name, ignoring case and
+// accents.
+func FindCountry(query string) (Country, bool) {
+	if country, ok := countryByCode[strings.ToUpper(strings.TrimSpace(query))]; ok {
+		return country, true
+	}
+	country, ok := countryByName[fold(query)]
+	return country, ok
+}
+
+// Countries returns every country with jurisdictions, sorted by code.
+func Countries() []Country {
+	return append([]Country(nil), countries...)
+}
+
+// Jurisdictions returns the jurisdictions in a country, sorted by code.
+func (c Country) Jurisdictions() []Jurisdiction {
+	return append([]Jurisdiction(nil), inCountry[c.Code]...)
+}
+
+// Country returns the country a jurisdiction is in.
+func (j Jurisdiction) Country() Country {
+	return countryByCode[j.countryCode()]
+}
+
+func (j Jurisdiction) countryCode() string {
+	if index := strings.IndexByte(j.Code, '-'); index != -1 {
+		return j.Code[:index]
+	}
+	return j.Code
+}
+
+// String describes a jurisdiction, like "US-CA (California, United
+// States of America)".
+func (j Jurisdiction) String() string {
+	country := j.Country().Name
+	if j.Name == "" || j.Name == country {
+		return j.Code + " (" + country + ")"
+	}
+	return j.Code + " (" + j.Name + ", " + country + ")"
+}
+
+// Suggest returns up to limit jurisdictions whose codes or names are
+// close to a query, closest first, for "did you mean" messages. A
+// limit of zero or less suggests nothing.
+func Suggest(query string, limit int) []Jurisdiction {
+	folded := fold(query)
+	if folded == "" || limit <= 0 {
+		return nil
+	}
+	threshold := len([]rune(folded)) / 3
+	if threshold < 1 {
+		threshold = 1
+	}
+	type candidate struct {
+		jurisdiction Jurisdiction
+		distance     int
+	}
+	var candidates []candidate
+	for _, jurisdiction := range jurisdictions {
+		distance := editDistance(folded, fold(jurisdiction.Code))
+		if jurisdiction.Name != "" {
+			name := fold(jurisdiction.Name)
+			if len(folded) >= 3 && strings.HasPrefix(name, folded) {
+				distance = 0
+			} else if nameDistance := editDistance(folded, name); nameDistance < distance {
+				distance = nameDistance
+			}
+		}
+		if distance <= threshold {
+			candidates = append(candidates, candidate{jurisdiction, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	suggestions := make([]Jurisdiction, len(candidates))
+	for index, candidate := range candidates {
+		suggestions[index] = candidate.jurisdiction
+	}
+	return suggestions
+}
+
+// editDistance returns the number of insertions, deletions,
+// substitutions, and transpositions of adjacent characters it takes to
+// turn one string into another.
+func editDistance(a, b string) int {
+	first, second := []rune(a), []rune(b)
+	distances := make([][]int, len(first)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(second)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+	for i := 1; i <= len(first); i++ {
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+			distances[i][j] = smallest(
+				distances[i-1][j]+1,
+				distances[i][j-1]+1,
+				distances[i-1][j-1]+cost,
+			)
+			if i > 1 && j > 1 && first[i-1] == second[j-2] && first[i-2] == second[j-1] {
+				distances[i][j] = smallest(distances[i][j], distances[i-2][j-2]+1)
+			}
+		}
+	}
+	return distances[len(first)][len(second)]
+}
+
+func smallest(values ...int) int {
+	least := values[0]
+	for _, value := range values[1:] {
+		if value < least {
+			least = value
+		}
+	}
+	return least
+}
+
+// unaccented maps accented Latin letters to plain ones.
+var unaccented = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "ā", "a", "ă", "a", "ą", "a",
+	"ç", "c", "ć", "c", "č", "c",
+	"ď", "d", "đ", "d",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ē", "e", "ė", "e", "ę", "e", "ě", "e",
+	"ğ", "g", "ģ", "g",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ī", "i", "į", "i", "ı", "i",
+	"ķ", "k",
+	"ĺ", "l", "ļ", "l", "ľ", "l", "ł", "l",
+	"ñ", "n", "ń", "n", "ņ", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "ō", "o", "ő", "o",
+	"ŕ", "r", "ř", "r",
+	"ś", "s", "ş", "s", "š", "s", "ș", "s",
+	"ţ", "t", "ť", "t", "ț", "t",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ū", "u", "ů", "u", "ű", "u", "ų", "u",
+	"ý", "y", "ÿ", "y",
+	"ź", "z", "ż", "z", "ž", "z",
+)
+
+// fold normalizes a name for comparison, ignoring case, accents,
+// punctuation, and spacing.
+func fold(name string) string {
+	name = unaccented.Replace(strings.ToLower(name))
+	var b strings.Builder
+	space := false
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
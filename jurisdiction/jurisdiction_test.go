@@ -0,0 +1,75 @@
+package jurisdiction
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCodes(t *testing.T) {
+	codes := Codes()
+	if !sort.StringsAreSorted(codes) {
+		t.Error("codes not sorted")
+	}
+	for index := 1; index < len(codes); index++ {
+		if codes[index] == codes[index-1] {
+			t.Errorf("duplicate code %s", codes[index])
+		}
+	}
+	for _, jurisdiction := range All() {
+		if jurisdiction.Country().Name == "" {
+			t.Errorf("%s: no country", jurisdiction.Code)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if found, ok := ByCode("us-ca"); !ok || found.Name != "California" {
+		t.Errorf("by code: %+v", found)
+	}
+	if found := ByName("baden-wurttemberg"); len(found) != 1 || found[0].Code != "DE-BW" {
+		t.Errorf("by name: %v", found)
+	}
+	if _, ok := ByCode("US-XX"); ok {
+		t.Error("found nonexistent code")
+	}
+	country, ok := FindCountry("germany")
+	if !ok || country.Code != "DE" {
+		t.Fatalf("country: %+v", country)
+	}
+	for _, jurisdiction := range country.Jurisdictions() {
+		if jurisdiction.Country() != country {
+			t.Errorf("%s not in %s", jurisdiction.Code, country.Code)
+		}
+	}
+	if found, _ := ByCode("DE-BE"); found.String() != "DE-BE (Berlin, Germany)" {
+		t.Errorf("described %q", found.String())
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	for query, expected := range map[string]string{
+		"Texsa":      "US-TX",
+		"califor":    "US-CA",
+		"DE-BX":      "DE-BB",
+		"Queensland": "AU-QLD",
+	} {
+		suggestions := Suggest(query, 3)
+		found := false
+		for _, suggestion := range suggestions {
+			if suggestion.Code == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: suggested %v", query, suggestions)
+		}
+	}
+	if suggestions := Suggest("zzzzzzzz", 3); len(suggestions) != 0 {
+		t.Errorf("suggested %v", suggestions)
+	}
+	for _, limit := range []int{0, -1} {
+		if suggestions := Suggest("Texsa", limit); len(suggestions) != 0 {
+			t.Errorf("limit %d: suggested %v", limit, suggestions)
+		}
+	}
+}
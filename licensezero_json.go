@@ -9,22 +9,47 @@ import (
 	"path"
 )
 
-func findLicenseZeroFiles(cwd string) (findings []finding, err error) {
-	entries, err := readAndStatDir(cwd)
+// findLicenseZeroFiles finds licensezero.json files in a directory and
+// below, skipping files, directories, and symlinks it can't read.
+func findLicenseZeroFiles(cwd string) (findings []finding, diagnostics []Diagnostic) {
+	if _, err := os.Stat(cwd); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return scanLicenseZeroFiles(cwd, make(map[string]bool))
+}
+
+// scanLicenseZeroFiles does the work of findLicenseZeroFiles, skipping
+// directories it has already visited by another path, so symlink
+// cycles, common in node_modules, don't keep it going forever.
+func scanLicenseZeroFiles(cwd string, visited map[string]bool) (findings []finding, diagnostics []Diagnostic) {
+	real, err := realpath.Realpath(cwd)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return findings, nil
-		}
-		return nil, err
+		return nil, []Diagnostic{diagnose(diagnosticFinder, &fileError{cwd, unwrapPathError(err)})}
+	}
+	if visited[real] {
+		return nil, nil
+	}
+	visited[real] = true
+	entries, linkErrors, err := readAndStatDir(cwd)
+	if err != nil {
+		return nil, []Diagnostic{diagnose(diagnosticFinder, err)}
+	}
+	for _, linkError := range linkErrors {
+		diagnostics = append(diagnostics, diagnose(diagnosticFinder, linkError))
 	}
 	for _, entry := range entries {
 		name := entry.Name()
 		if name == "licensezero.json" {
-			findings, err := ReadLicenseZeroJSON(cwd)
+			found, err := ReadLicenseZeroJSON(cwd)
 			if err != nil {
-				return nil, err
+				diagnostics = append(diagnostics, Diagnostic{
+					Path:   path.Join(cwd, name),
+					Source: diagnosticParser,
+					Cause:  err,
+				})
+				continue
 			}
-			for _, finding := range findings {
+			for _, finding := range found {
 				if alreadyHave(findings, &finding) {
 					continue
 				}
@@ -39,11 +64,9 @@ func findLicenseZeroFiles(cwd string) (findings []finding, err error) {
 			}
 		} else if entry.IsDir() {
 			directory := path.Join(cwd, name)
-			below, err := findLicenseZeroFiles(directory)
-			if err != nil {
-				return nil, err
-			}
+			below, problems := scanLicenseZeroFiles(directory, visited)
 			findings = append(findings, below...)
+			diagnostics = append(diagnostics, problems...)
 		}
 	}
 	return
@@ -93,19 +116,24 @@ func ReadLicenseZeroJSON(directoryPath string) (findings []finding, err error) {
 		return nil, err
 	}
 	var unstructured interface{}
-	json.Unmarshal(data, &unstructured)
+	err = json.Unmarshal(data, &unstructured)
+	if err != nil {
+		return nil, err
+	}
 	parsed, err := ParseArtifact(unstructured)
+	if err != nil {
+		return nil, err
+	}
 	for _, offer := range parsed.Offers() {
 		item := finding{
 			Path:    directoryPath,
 			API:     offer.API,
 			OfferID: offer.OfferID,
+			Public:  offer.Public,
 		}
 		realDirectory, err := realpath.Realpath(directoryPath)
-		if err != nil {
+		if err == nil {
 			item.Path = realDirectory
-		} else {
-			item.Path = directoryPath
 		}
 		findings = append(findings, item)
 	}
@@ -1,7 +1,188 @@
 package main
 
-import "fmt"
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Rev is the revision of the CLI, set at build time.
+var Rev string
+
+type subcommand struct {
+	Summary string
+	Handler func(args []string, env *environment) int
+}
+
+var subcommands = map[string]subcommand{
+	"artifact":   artifactCommand,
+	"buy":        buyCommand,
+	"dev-server": devServerCommand,
+	"doctor":     doctorCommand,
+	"identify":   identifyCommand,
+	"issue":      issueCommand,
+	"keygen":     keygenCommand,
+	"login":      loginCommand,
+	"logout":     logoutCommand,
+	"migrate":    migrateCommand,
+	"offer":      offerCommand,
+	"quote":      quoteCommand,
+	"receipts":   receiptsCommand,
+	"rekey":      rekeyCommand,
+	"schema":     schemaCommand,
+	"validate":   validateCommand,
+}
+
+// environment holds what subcommands need from the world outside.
+type environment struct {
+	Config string
+	CWD    string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Getenv func(string) string
+	reader *bufio.Reader
+	// configureNetwork sets up apiClient. Commands call it through
+	// setUpNetwork only when they need to reach an API, so broken
+	// network settings don't break other commands.
+	configureNetwork func() error
+}
+
+// setUpNetwork prepares apiClient for requests to licensing APIs.
+func (env *environment) setUpNetwork() error {
+	if env.configureNetwork == nil {
+		return nil
+	}
+	return env.configureNetwork()
+}
+
+// readLine reads a line of input, without its line ending.
+func (env *environment) readLine() (string, error) {
+	if env.reader == nil {
+		env.reader = bufio.NewReader(env.Stdin)
+	}
+	line, err := env.reader.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
 
 func main() {
-	fmt.Println("vim-go")
+	config, err := configPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not find configuration directory:", err)
+		os.Exit(1)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read working directory:", err)
+		os.Exit(1)
+	}
+	os.Exit(run(os.Args[1:], &environment{
+		Config: config,
+		CWD:    cwd,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Getenv: os.Getenv,
+		configureNetwork: func() error {
+			return configureAPIClient(config, os.Getenv)
+		},
+	}))
+}
+
+func run(args []string, env *environment) int {
+	if len(args) == 0 {
+		printUsage(env.Stderr)
+		return 1
+	}
+	name := args[0]
+	if name == "help" || name == "--help" || name == "-h" {
+		printUsage(env.Stdout)
+		return 0
+	}
+	if name == "version" || name == "--version" || name == "-v" {
+		if Rev == "" {
+			fmt.Fprintln(env.Stdout, "Development Build")
+		} else {
+			fmt.Fprintln(env.Stdout, Rev)
+		}
+		return 0
+	}
+	command, ok := subcommands[name]
+	if !ok {
+		fmt.Fprintf(env.Stderr, "Unknown subcommand: %s\n", name)
+		printUsage(env.Stderr)
+		return 1
+	}
+	return command.Handler(args[1:], env)
+}
+
+func printUsage(output io.Writer) {
+	fmt.Fprintln(output, "Usage: licensezero <subcommand> [flags]")
+	fmt.Fprintln(output)
+	fmt.Fprintln(output, "Subcommands:")
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(output, "  %-12s %s\n", name, subcommands[name].Summary)
+	}
+}
+
+// configPath returns the path of the CLI's configuration directory.
+func configPath() (string, error) {
+	if fromEnvironment := os.Getenv("LICENSEZERO_CONFIG"); fromEnvironment != "" {
+		return fromEnvironment, nil
+	}
+	userConfig, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(userConfig, "licensezero"), nil
+}
+
+// parseInterspersed parses flags that may come before, after, or
+// between positional arguments, and returns the positional arguments.
+func parseInterspersed(flagSet *flag.FlagSet, args []string) (positional []string, err error) {
+	for {
+		err = flagSet.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = flagSet.Args()
+		if len(args) == 0 {
+			return
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// dispatch runs a subcommand's own subcommands.
+func dispatch(name string, subcommands map[string]subcommand, args []string, env *environment) int {
+	if len(args) != 0 {
+		if command, ok := subcommands[args[0]]; ok {
+			return command.Handler(args[1:], env)
+		}
+		fmt.Fprintf(env.Stderr, "Unknown subcommand: %s %s\n", name, args[0])
+	}
+	fmt.Fprintf(env.Stderr, "Usage: licensezero %s <subcommand> [flags]\n\nSubcommands:\n", name)
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(env.Stderr, "  %-12s %s\n", name, subcommands[name].Summary)
+	}
+	return 1
 }
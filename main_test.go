@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUnknownSubcommand(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"nonexistent"}, env) != 1 {
+			t.Error("did not fail")
+		}
+		if !strings.Contains(stderr.String(), "Unknown subcommand") {
+			t.Error("did not report unknown subcommand")
+		}
+	})
+}
+
+func newTestEnvironment(directory string, stdin string, variables map[string]string) (env *environment, stdout *bytes.Buffer, stderr *bytes.Buffer) {
+	stdout = new(bytes.Buffer)
+	stderr = new(bytes.Buffer)
+	env = &environment{
+		Config: directory,
+		CWD:    directory,
+		Stdin:  strings.NewReader(stdin),
+		Stdout: stdout,
+		Stderr: stderr,
+		Getenv: func(name string) string {
+			return variables[name]
+		},
+	}
+	return
+}
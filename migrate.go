@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const migrateUsage = `Convert data from the previous License Zero CLI.
+
+Usage:
+  licensezero migrate [--api URL]
+
+Flags:
+  --api URL    licensing API for migrated offers and receipts
+               (default "` + defaultAPI + `")
+
+Converts the "licensezero" array in licensezero.json or package.json
+in the working directory to a list of offers. In the configuration
+directory, imports identity.json as an identity and licensor.json as a
+licensor account, and converts licenses/*.json to receipts in the
+legacy directory. Old files are left in place.
+
+Converted licenses keep the signatures License Zero made for the old
+format, which can't be verified as receipts. quote and buy list items
+with legacy receipts apart, without offering to license them again,
+and validate reports them as invalid. Use receipts sync to fetch
+current receipts, if the API has them.
+`
+
+var migrateCommand = subcommand{
+	Summary: "Convert data from the previous CLI.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("migrate", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, migrateUsage) }
+		apiFlag := flagSet.String("api", defaultAPI, "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 {
+			flagSet.Usage()
+			return 1
+		}
+		api := strings.TrimRight(*apiFlag, "/")
+		if err := validateAPI(api); err != nil {
+			fmt.Fprintln(env.Stderr, "Invalid API:", err)
+			return 1
+		}
+		m := &migration{env: env, api: api}
+		m.artifactMetadata()
+		m.identity()
+		m.licensor()
+		m.licenses()
+		m.waivers()
+		if m.migrated == 0 && len(m.failures) == 0 {
+			fmt.Fprintln(env.Stdout, "Nothing to migrate.")
+			return 0
+		}
+		for _, failure := range m.failures {
+			fmt.Fprintln(env.Stderr, "Could not convert "+failure)
+		}
+		fmt.Fprintf(env.Stdout, "Migrated %d, could not convert %d.\n", m.migrated, len(m.failures))
+		if len(m.failures) != 0 {
+			return 1
+		}
+		return 0
+	},
+}
+
+// migration tracks what migrate has converted, and what it couldn't.
+type migration struct {
+	env      *environment
+	api      string
+	migrated int
+	failures []string
+}
+
+func (m *migration) done(format string, args ...interface{}) {
+	fmt.Fprintf(m.env.Stdout, format+"\n", args...)
+	m.migrated++
+}
+
+func (m *migration) fail(filePath string, err error) {
+	m.failures = append(m.failures, filePath+": "+err.Error())
+}
+
+// legacyArtifactEntry is an entry in the "licensezero" array of the
+// previous CLI's metadata.
+type legacyArtifactEntry struct {
+	License struct {
+		ProjectID string `json:"projectID"`
+		Terms     string `json:"terms"`
+		Version   string `json:"version"`
+	} `json:"license"`
+}
+
+// artifactMetadata converts legacy metadata in the working directory.
+func (m *migration) artifactMetadata() {
+	metadata, err := openArtifactMetadata(m.env.CWD)
+	if err != nil {
+		m.fail(path.Join(m.env.CWD, "licensezero.json"), err)
+		return
+	}
+	if !metadata.exists {
+		return
+	}
+	var root map[string]json.RawMessage
+	if json.Unmarshal(metadata.data, &root) != nil {
+		// Not legacy metadata. Other commands report invalid files.
+		return
+	}
+	var entries []legacyArtifactEntry
+	if json.Unmarshal(root["licensezero"], &entries) != nil {
+		return
+	}
+	// Leave the file as it was if any of it can't be converted, so
+	// nothing is lost.
+	failures := len(m.failures)
+	converted := artifact1_0_0Pre{OfferArray: []artifactOffer1_0_0Pre{}}
+	for index, entry := range entries {
+		offer, err := convertLegacyArtifactEntry(m.api, entry)
+		if err != nil {
+			m.fail(fmt.Sprintf("%s: licensezero[%d]", metadata.Path, index), err)
+			continue
+		}
+		converted.OfferArray = append(converted.OfferArray, offer)
+	}
+	if metadata.Property == "" {
+		for key := range root {
+			if key != "licensezero" {
+				m.fail(metadata.Path, errors.New("unknown property "+key))
+			}
+		}
+	}
+	if len(m.failures) != failures {
+		return
+	}
+	if metadata.Property == "" {
+		data, err := json.MarshalIndent(converted, "", "  ")
+		if err != nil {
+			m.fail(metadata.Path, err)
+			return
+		}
+		metadata.data = append(data, '\n')
+	} else {
+		object, err := locateObject(metadata.data, 0)
+		if err != nil {
+			m.fail(metadata.Path, err)
+			return
+		}
+		metadata.data, err = setMember(metadata.data, &object, metadata.Property, converted)
+		if err != nil {
+			m.fail(metadata.Path, err)
+			return
+		}
+	}
+	err = metadata.Save()
+	if err != nil {
+		m.fail(metadata.Path, err)
+		return
+	}
+	m.done("Converted %s.", metadata.Path)
+}
+
+func convertLegacyArtifactEntry(api string, entry legacyArtifactEntry) (offer artifactOffer1_0_0Pre, err error) {
+	if !validUUID.MatchString(entry.License.ProjectID) {
+		return offer, errors.New("missing or invalid projectID")
+	}
+	offer.API = api
+	offer.OfferID = entry.License.ProjectID
+	if entry.License.Terms != "" {
+		public := legacyPublicLicense(entry.License.Terms, entry.License.Version)
+		if licenseTypeOf(public) == unknown {
+			return offer, errors.New("unknown terms " + entry.License.Terms + " " + entry.License.Version)
+		}
+		offer.Public = public
+	}
+	return offer, nil
+}
+
+// legacyPublicLicense returns the identifier for the public license
+// the previous CLI called by name and version.
+func legacyPublicLicense(terms string, version string) string {
+	if terms == "" {
+		return ""
+	}
+	return strings.ToUpper(terms[:1]) + strings.ToLower(terms[1:]) + "-" + version
+}
+
+// identity imports identity.json, the previous CLI's only identity.
+func (m *migration) identity() {
+	filePath := path.Join(m.env.Config, "identity.json")
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		m.fail(filePath, err)
+		return
+	}
+	var identity Licensee
+	err = json.Unmarshal(data, &identity)
+	if err != nil {
+		m.fail(filePath, err)
+		return
+	}
+	if _, err := os.Stat(identityPath(m.env.Config, identity.EMail)); err == nil {
+		return
+	}
+	if code, err := resolveJurisdiction(identity.Jurisdiction); err == nil {
+		identity.Jurisdiction = code
+	}
+	err = validateIdentity(&identity)
+	if err == nil {
+		err = writeIdentity(m.env.Config, &identity)
+	}
+	if err != nil {
+		m.fail(filePath, err)
+		return
+	}
+	m.done("Imported identity %s <%s>.", identity.Name, identity.EMail)
+}
+
+// licensor imports licensor.json, the previous CLI's only account.
+func (m *migration) licensor() {
+	filePath := path.Join(m.env.Config, "licensor.json")
+	data, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		m.fail(filePath, err)
+		return
+	}
+	var legacy struct {
+		LicensorID string `json:"licensorID"`
+		Token      string `json:"token"`
+	}
+	err = json.Unmarshal(data, &legacy)
+	if err != nil {
+		m.fail(filePath, err)
+		return
+	}
+	if !validUUID.MatchString(legacy.LicensorID) || legacy.Token == "" {
+		m.fail(filePath, errors.New("missing licensorID or token"))
+		return
+	}
+	if _, err := os.Stat(accountPath(m.env.Config, m.api, legacy.LicensorID)); err == nil {
+		return
+	}
+	passphrase, err := readStorePassphrase(m.env)
+	if err == nil {
+		err = writeAccount(m.env.Config, &accountRecord{
+			APIURL: m.api,
+			ID:     legacy.LicensorID,
+			secret: legacy.Token,
+		}, passphrase)
+	}
+	if err != nil {
+		m.fail(filePath, err)
+		return
+	}
+	m.done("Imported licensor account %s.", legacy.LicensorID)
+}
+
+// legacyLicense is a license file the previous CLI saved in licenses/.
+// Its manifest may be a JSON object or a string of JSON.
+type legacyLicense struct {
+	Manifest  json.RawMessage `json:"manifest"`
+	Document  string          `json:"document"`
+	PublicKey string          `json:"publicKey"`
+	Signature string          `json:"signature"`
+}
+
+type legacyManifest struct {
+	Date      string   `json:"date"`
+	OrderID   string   `json:"orderID"`
+	ProjectID string   `json:"projectID"`
+	Price     uint     `json:"price"`
+	Licensee  Licensee `json:"licensee"`
+	Licensor  Licensor `json:"licensor"`
+	Project   struct {
+		ProjectID string `json:"projectID"`
+	} `json:"project"`
+}
+
+// legacyReceiptsDirectory holds receipts converted from the previous
+// CLI's licenses, apart from receipts the CLI can verify.
+const legacyReceiptsDirectory = "legacy"
+
+// licenses converts license files to receipts in the legacy directory.
+func (m *migration) licenses() {
+	directory := path.Join(m.env.Config, "licenses")
+	for _, filePath := range m.listJSON(directory) {
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			m.fail(filePath, err)
+			continue
+		}
+		receipt, err := convertLegacyLicense(m.api, data)
+		if err != nil {
+			m.fail(filePath, err)
+			continue
+		}
+		encoded, err := json.MarshalIndent(receipt, "", "  ")
+		if err != nil {
+			m.fail(filePath, err)
+			continue
+		}
+		saved, err := saveReceiptIn(path.Join(m.env.Config, legacyReceiptsDirectory), receipt, encoded)
+		if err != nil {
+			m.fail(filePath, err)
+			continue
+		}
+		if saved {
+			m.done("Converted license for order %s, offer %s, to a legacy receipt.", receipt.OrderID(), receipt.OfferID())
+		}
+	}
+}
+
+func convertLegacyLicense(api string, data []byte) (*receipt1_0_0Pre, error) {
+	var legacy legacyLicense
+	err := json.Unmarshal(data, &legacy)
+	if err != nil {
+		return nil, err
+	}
+	manifestData := []byte(legacy.Manifest)
+	var quoted string
+	if json.Unmarshal(legacy.Manifest, &quoted) == nil {
+		manifestData = []byte(quoted)
+	}
+	var manifest legacyManifest
+	err = json.Unmarshal(manifestData, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: %v", err)
+	}
+	offerID := manifest.ProjectID
+	if offerID == "" {
+		offerID = manifest.Project.ProjectID
+	}
+	receipt := &receipt1_0_0Pre{
+		Key:       legacy.PublicKey,
+		Signature: legacy.Signature,
+		License: license1_0_0Pre{
+			Form: legacy.Document,
+			Values: licenseValues1_0_0Pre{
+				API:       api,
+				OfferID:   offerID,
+				OrderID:   manifest.OrderID,
+				Effective: manifest.Date,
+				Licensee:  manifest.Licensee,
+				Licensor:  manifest.Licensor,
+			},
+		},
+	}
+	if manifest.Price != 0 {
+		// The previous API sold only in US cents.
+		receipt.License.Values.Price = &Price{Amount: manifest.Price, Currency: "USD"}
+	}
+	problems, err := checkV1ReceiptSchema(receipt)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) != 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return receipt, nil
+}
+
+// waivers reports waivers, which receipts can't represent.
+func (m *migration) waivers() {
+	for _, filePath := range m.listJSON(path.Join(m.env.Config, "waivers")) {
+		m.fail(filePath, errors.New("waivers have no equivalent"))
+	}
+}
+
+// listJSON lists the JSON files in a directory, if it exists.
+func (m *migration) listJSON(directory string) (paths []string) {
+	entries, err := ioutil.ReadDir(directory)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		m.fail(directory, err)
+		return nil
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			paths = append(paths, path.Join(directory, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+const testLegacyManifest = `{\"date\":\"2018-11-13T20:20:39Z\",\"orderID\":\"2c743a84-09ce-4549-9f0d-19d8f53462bb\",\"price\":1000,\"licensee\":{\"email\":\"licensee@example.com\",\"jurisdiction\":\"US-TX\",\"name\":\"Joe Licensee\"},\"licensor\":{\"email\":\"licensor@example.com\",\"jurisdiction\":\"US-CA\",\"licensorID\":\"59e70a4d-ffee-4e9d-a526-7a9ff9161664\",\"name\":\"Jane Licensor\"},\"project\":{\"projectID\":\"9aab7058-599a-43db-9449-5fc0971ecbfa\"}}`
+
+func writeTestFiles(t *testing.T, directory string, files map[string]string) {
+	for name, content := range files {
+		filePath := path.Join(directory, name)
+		err := os.MkdirAll(path.Dir(filePath), 0700)
+		if err == nil {
+			err = ioutil.WriteFile(filePath, []byte(content), 0600)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		writeTestFiles(t, directory, map[string]string{
+			"licensezero.json": `{
+  "licensezero": [
+    {
+      "license": {
+        "projectID": "9aab7058-599a-43db-9449-5fc0971ecbfa",
+        "terms": "parity",
+        "version": "7.0.0"
+      },
+      "licensorSignature": "00"
+    },
+    {
+      "license": {
+        "terms": "parity",
+        "version": "7.0.0"
+      }
+    }
+  ]
+}`,
+			"identity.json": `{"name": "Joe Licensee", "email": "licensee@example.com", "jurisdiction": "US-TX"}`,
+			"licensor.json": `{"licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664", "token": "secret"}`,
+			"licenses/9aab7058-599a-43db-9449-5fc0971ecbfa.json": `{
+  "manifest": "` + testLegacyManifest + `",
+  "document": "Legacy license text.",
+  "publicKey": "` + strings.Repeat("a", 64) + `",
+  "signature": "` + strings.Repeat("b", 128) + `"
+}`,
+			"waivers/d56ee0a6-4ed3-4793-9485-6135644c158f.json": `{}`,
+		})
+		env, stdout, stderr := newTestEnvironment(directory, "", map[string]string{
+			passphraseEnvironmentVariable: "test",
+		})
+		if run([]string{"migrate"}, env) == 0 {
+			t.Error("reported no failures")
+		}
+		for _, expected := range []string{
+			"Imported identity Joe Licensee <licensee@example.com>.",
+			"Imported licensor account 59e70a4d-ffee-4e9d-a526-7a9ff9161664.",
+			"Converted license for order 2c743a84-09ce-4549-9f0d-19d8f53462bb, offer 9aab7058-599a-43db-9449-5fc0971ecbfa, to a legacy receipt.",
+			"Migrated 3, could not convert 2.",
+		} {
+			if !strings.Contains(stdout.String(), expected) {
+				t.Errorf("missing %q in output:\n%s", expected, stdout.String())
+			}
+		}
+		for _, expected := range []string{"licensezero[1]: missing or invalid projectID", "waivers have no equivalent"} {
+			if !strings.Contains(stderr.String(), expected) {
+				t.Errorf("missing %q in errors:\n%s", expected, stderr.String())
+			}
+		}
+
+		if _, err := ReadLicenseZeroJSON(directory); err == nil {
+			t.Error("converted licensezero.json with an invalid entry")
+		}
+		identities, _, _ := ReadIdentities(directory)
+		accounts, _, _ := ReadAccounts(directory)
+		receipts, _, _ := ReadReceipts(directory)
+		if len(identities) != 1 || len(accounts) != 1 || len(receipts) != 0 {
+			t.Fatalf("%d identities, %d accounts, %d receipts", len(identities), len(accounts), len(receipts))
+		}
+		legacy, err := readReceipt(path.Join(directory, legacyReceiptsDirectory, "2c743a84-09ce-4549-9f0d-19d8f53462bb-9aab7058-599a-43db-9449-5fc0971ecbfa.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price := legacy.(receipt1_0_0Pre).License.Values.Price; price == nil || *price != (Price{1000, "USD"}) {
+			t.Errorf("receipt price %v", price)
+		}
+
+		writeTestFiles(t, directory, map[string]string{
+			"licensezero.json": `{"licensezero": [{"license": {"projectID": "9aab7058-599a-43db-9449-5fc0971ecbfa", "terms": "parity", "version": "7.0.0"}}]}`,
+		})
+		os.RemoveAll(path.Join(directory, "waivers"))
+		env, stdout, stderr = newTestEnvironment(directory, "", nil)
+		if run([]string{"migrate"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		if stdout.String() != "Converted "+path.Join(directory, "licensezero.json")+".\nMigrated 1, could not convert 0.\n" {
+			t.Errorf("second migration:\n%s", stdout.String())
+		}
+		offers, err := ReadLicenseZeroJSON(directory)
+		if err != nil || len(offers) != 1 || offers[0].API != defaultAPI || offers[0].Public != "Parity-7.0.0" {
+			t.Errorf("read %+v, %v", offers, err)
+		}
+
+		env, stdout, _ = newTestEnvironment(directory, "", nil)
+		if run([]string{"migrate"}, env) != 0 || stdout.String() != "Nothing to migrate.\n" {
+			t.Errorf("third migration:\n%s", stdout.String())
+		}
+	})
+}
+
+func TestMigratePackageJSON(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		writeTestFiles(t, directory, map[string]string{
+			"package.json": `{
+  "name": "example",
+  "licensezero": [
+    {"license": {"projectID": "9aab7058-599a-43db-9449-5fc0971ecbfa", "terms": "prosperity", "version": "3.0.0"}}
+  ],
+  "version": "1.0.0"
+}
+`,
+		})
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"migrate", "--api", "https://licensing.example.com"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		data, err := ioutil.ReadFile(path.Join(directory, "package.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `"api": "https://licensing.example.com"`) ||
+			!strings.Contains(string(data), `"public": "Prosperity-3.0.0"`) ||
+			!strings.Contains(string(data), `"version": "1.0.0"`) {
+			t.Errorf("migrated to:\n%s", data)
+		}
+	})
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"errors"
+	"flag"
+	"fmt"
 	"github.com/mitchellh/mapstructure"
-	"github.com/xeipuuv/gojsonschema"
+	"strconv"
+	"strings"
 )
 
 // Offer represents an offer to sell licenses.
@@ -11,17 +14,101 @@ type Offer struct {
 	URL        string  `mapstructure:"url"`
 	LicensorID string  `mapstructure:"licensorID"`
 	Pricing    Pricing `mapstructure:"pricing"`
+	// Signature is the API's signature of the offer, if any.
+	Signature string `mapstructure:"signature"`
+	// unstructured is the offer as parsed, for checking its signature.
+	unstructured interface{}
+}
+
+var errUnsignedOffer = errors.New("unsigned offer")
+
+// Verify checks the offer's signature against the API's public key,
+// and that the API signed it as the offer with offerID.
+func (o *Offer) Verify(publicKey string, api string, offerID string) error {
+	if o.Signature == "" {
+		return errUnsignedOffer
+	}
+	if o.unstructured == nil {
+		return errors.New("no signed offer data")
+	}
+	signed, err := offerSigningData(api, offerID, o.unstructured)
+	if err != nil {
+		return err
+	}
+	err = checkSignature(publicKey, o.Signature, signed)
+	if err != nil {
+		return errors.New("invalid offer signature for offer " + offerID)
+	}
+	return nil
+}
+
+// offerSigningData returns what an API signs for an offer: canonical
+// JSON of the API's origin, the offer ID, and the offer without its
+// signature. Signing the origin and ID keeps one genuine offer from
+// passing for another.
+func offerSigningData(api string, offerID string, unstructured interface{}) ([]byte, error) {
+	origin, err := apiOrigin(api)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := unstructured.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("offer is not an object")
+	}
+	unsigned := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		if key != "signature" {
+			unsigned[key] = value
+		}
+	}
+	return canonicalJSON(map[string]interface{}{
+		"api":     origin,
+		"offer":   unsigned,
+		"offerID": offerID,
+	})
 }
 
 // Pricing represents a price list.
 type Pricing struct {
-	Single    Price `mapstructure:"single"`
+	// Single is the price of a license for one person.
+	Single Price `mapstructure:"single"`
+	// Site is the price of a license for any number of people.
+	Site Price `mapstructure:"site"`
+	// Tiers are prices of licenses for teams, by maximum team size.
+	Tiers map[uint]Price `mapstructure:"-"`
+	// Relicense is the price to relicense the artifact.
 	Relicense Price `mapstructure:"relicense"`
 }
 
+// PriceFor returns the price of licenses for a team. It chooses the
+// smallest tier large enough for the team, or else the site license.
+// Single licenses cover one person each, so they don't price teams.
+func (p Pricing) PriceFor(seats uint) (Price, error) {
+	if seats == 0 {
+		return Price{}, errors.New("no seats")
+	}
+	if seats == 1 && p.Single.Currency != "" {
+		return p.Single, nil
+	}
+	var best uint
+	for size := range p.Tiers {
+		if size >= seats && (best == 0 || size < best) {
+			best = size
+		}
+	}
+	if best != 0 {
+		return p.Tiers[best], nil
+	}
+	if p.Site.Currency != "" {
+		return p.Site, nil
+	}
+	return Price{}, fmt.Errorf("no price for %d seats", seats)
+}
+
 const offer1_0_0PreSchema = `{
   "$schema": "http://json-schema.org/schema#",
   "$id": "https://schemas.licensezero.com/1.0.0-pre/offer.json",
+  "title": "offer of private licenses",
   "type": "object",
   "required": [
     "licensorID",
@@ -31,10 +118,12 @@ const offer1_0_0PreSchema = `{
   "additionalProperties": true,
   "properties": {
     "licensorID": {
+      "title": "licensor identifier",
       "type": "string",
       "format": "uuid"
     },
     "pricing": {
+      "title": "prices by license type, and by seats for team licenses",
       "type": "object",
       "properties": {
         "single": {
@@ -42,50 +131,181 @@ const offer1_0_0PreSchema = `{
         },
         "site": {
           "$ref": "price.json"
+        },
+        "relicense": {
+          "$ref": "price.json"
         }
       },
       "patternProperties": {
-        "^d+$": {
+        "^[0-9]+$": {
           "$ref": "price.json"
         }
       }
     },
     "url": {
+      "title": "homepage of the artifact",
       "type": "string",
       "format": "uri"
+    },
+    "signature": {
+      "title": "API signature of its origin, the offer ID, and the offer",
+      "$ref": "signature.json"
     }
   }
 }`
 
 // ParseOffer parses instructed offer data.
 func ParseOffer(unstructured interface{}) (Offer, error) {
-	if validV1Offer(unstructured) {
-		return parseV1Offer(unstructured), nil
+	version, data, err := detectVersion("offer", unstructured)
+	if err != nil {
+		return Offer{}, err
+	}
+	var offer Offer
+	switch version {
+	case "1.0.0-pre":
+		offer = parseV1Offer(data)
 	}
-	return Offer{}, errors.New("unknown schema")
+	offer.unstructured = unstructured
+	return offer, nil
 }
 
-var v1OfferSchema *gojsonschema.Schema = nil
-
 func validV1Offer(unstructured interface{}) bool {
-	if v1OfferSchema == nil {
-		schema, err := schemaLoader().Compile(
-			gojsonschema.NewStringLoader(offer1_0_0PreSchema),
-		)
-		if err != nil {
-			panic(err)
-		}
-		v1OfferSchema = schema
-	}
-	dataLoader := gojsonschema.NewGoLoader(unstructured)
-	result, err := v1OfferSchema.Validate(dataLoader)
-	if err != nil {
-		return false
-	}
-	return result.Valid()
+	return schemas.valid(schemaID("1.0.0-pre", "offer"), unstructured)
 }
 
 func parseV1Offer(unstructured interface{}) (o Offer) {
 	mapstructure.Decode(unstructured, &o)
+	object, _ := unstructured.(map[string]interface{})
+	pricing, _ := object["pricing"].(map[string]interface{})
+	for key, value := range pricing {
+		size, err := strconv.ParseUint(key, 10, 0)
+		if err != nil || size == 0 {
+			continue
+		}
+		var price Price
+		if mapstructure.Decode(value, &price) != nil {
+			continue
+		}
+		if o.Pricing.Tiers == nil {
+			o.Pricing.Tiers = make(map[uint]Price)
+		}
+		o.Pricing.Tiers[uint(size)] = price
+	}
 	return
 }
+
+const offerUsage = `Create an offer to sell licenses for the artifact in the
+current directory, and add it to licensezero.json or package.json.
+
+Usage:
+  licensezero offer --price PRICE --url URL [flags]
+
+Flags:
+  --price PRICE       price of a license, like 1000USD
+  --relicense PRICE   price to relicense, like 100000USD
+  --url URL           homepage of the artifact
+  --public ID         public license identifier, like Parity-7.0.0
+  --api URL           licensing API (default "` + defaultAPI + `")
+  --licensor ID       licensor account, if logged in to more than one
+`
+
+var offerCommand = subcommand{
+	Summary: "Offer licenses for sale.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("offer", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, offerUsage) }
+		price := flagSet.String("price", "", "")
+		relicense := flagSet.String("relicense", "", "")
+		homepage := flagSet.String("url", "", "")
+		public := flagSet.String("public", "", "")
+		api := flagSet.String("api", defaultAPI, "")
+		licensorID := flagSet.String("licensor", "", "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 || *price == "" || *homepage == "" {
+			flagSet.Usage()
+			return 1
+		}
+		request := OfferRequest{URL: *homepage}
+		single, err := parsePrice(*price)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, err)
+			return 1
+		}
+		request.Pricing.Single = single
+		if *relicense != "" {
+			relicensePrice, err := parsePrice(*relicense)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, err)
+				return 1
+			}
+			request.Pricing.Relicense = &relicensePrice
+		}
+
+		accounts, _, err := ReadAccounts(env.Config)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read accounts:", err)
+			return 1
+		}
+		account, err := selectAccount(accounts, strings.TrimRight(*api, "/"), *licensorID)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, err)
+			return 1
+		}
+		err = unlockAccount(env, account)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read token:", err)
+			return 1
+		}
+		request.LicensorID = account.LicensorID()
+
+		if err := env.setUpNetwork(); err != nil {
+			fmt.Fprintln(env.Stderr, "Could not configure network access:", err)
+			return 1
+		}
+		offerID, err := CreateOffer(account, request)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not create offer:", err)
+			return 1
+		}
+		fmt.Fprintln(env.Stdout, offerID)
+		metadata, err := openArtifactMetadata(env.CWD)
+		if err == nil {
+			err = metadata.AddOffer(ArtifactOffer{
+				API:     account.API(),
+				OfferID: offerID,
+				Public:  *public,
+			})
+		}
+		if err == nil {
+			err = metadata.Save()
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "Created offer %s, but could not add it to artifact metadata: %v\n", offerID, err)
+			return 1
+		}
+		fmt.Fprintln(env.Stderr, "Added offer to "+metadata.Path+".")
+		return 0
+	},
+}
+
+// selectAccount finds the one account for an API, or the account for
+// a specific licensor.
+func selectAccount(accounts []Account, api string, licensorID string) (Account, error) {
+	var matches []Account
+	for _, account := range accounts {
+		if account.API() != api {
+			continue
+		}
+		if licensorID != "" && account.LicensorID() != licensorID {
+			continue
+		}
+		matches = append(matches, account)
+	}
+	if len(matches) == 0 {
+		return nil, errors.New("Not logged in to " + api + ". Run licensezero login first.")
+	}
+	if len(matches) > 1 {
+		return nil, errors.New("Logged in to " + api + " as more than one licensor. Use --licensor.")
+	}
+	return matches[0], nil
+}
@@ -2,6 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +37,172 @@ func TestParseOffer(t *testing.T) {
 		t.Error("failed to parse licensorID")
 	}
 }
+
+func TestOfferCommand(t *testing.T) {
+	licensorID := "59e70a4d-ffee-4e9d-a526-7a9ff9161664"
+	offerID := "9aab7058-599a-43db-9449-5fc0971ecbfa"
+	var received OfferRequest
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/offers" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid token"}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"offerID":"` + offerID + `"}`))
+	}))
+	defer server.Close()
+	withAPIClient(server.Client(), func() {
+		WithTestDir(t, func(directory string) {
+			variables := map[string]string{passphraseEnvironmentVariable: "passphrase"}
+			env, _, stderr := newTestEnvironment(directory, "secret-token\n", variables)
+			if run([]string{"login", "--api", server.URL, "--licensor", licensorID}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+
+			packageJSON := path.Join(directory, "package.json")
+			err := ioutil.WriteFile(packageJSON, []byte(`{
+    "name": "example",
+    "version": "1.0.0",
+    "dependencies": {}
+}
+`), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			env, stdout, stderr := newTestEnvironment(directory, "", variables)
+			code := run([]string{
+				"offer",
+				"--api", server.URL,
+				"--price", "1000USD",
+				"--relicense", "100000USD",
+				"--url", "https://example.com",
+				"--public", "Parity-7.0.0",
+			}, env)
+			if code != 0 {
+				t.Fatal(stderr.String())
+			}
+			if strings.TrimSpace(stdout.String()) != offerID {
+				t.Error("did not print offerID")
+			}
+			if received.LicensorID != licensorID || received.Pricing.Single.Amount != 1000 {
+				t.Error("did not send offer details")
+			}
+			if received.Pricing.Relicense == nil || received.Pricing.Relicense.Amount != 100000 {
+				t.Error("did not send relicense price")
+			}
+
+			data, err := ioutil.ReadFile(packageJSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := `{
+    "name": "example",
+    "version": "1.0.0",
+    "dependencies": {},
+    "licensezero": {
+        "offers": [
+            {
+                "api": "` + server.URL + `",
+                "offerID": "` + offerID + `",
+                "public": "Parity-7.0.0"
+            }
+        ]
+    }
+}
+`
+			if string(data) != expected {
+				t.Errorf("wrote package.json:\n%s", data)
+			}
+		})
+	})
+}
+
+func withAPIClient(client *http.Client, script func()) {
+	original := apiClient
+	apiClient = newAPIClient(client.Transport)
+	defer func() { apiClient = original }()
+	script()
+}
+
+func TestPricingTiers(t *testing.T) {
+	bytes := []byte(`{
+	"url": "http://example.com",
+	"licensorID": "d56ee0a6-4ed3-4793-9485-6135644c158f",
+	"pricing": {
+		"single": {"currency": "USD", "amount": 1000},
+		"10": {"currency": "USD", "amount": 5000},
+		"100": {"currency": "USD", "amount": 20000},
+		"site": {"currency": "USD", "amount": 50000},
+		"relicense": {"currency": "USD", "amount": 1000000}
+	}
+}`)
+	var unstructured interface{}
+	err := json.Unmarshal(bytes, &unstructured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer, err := ParseOffer(unstructured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pricing := offer.Pricing
+	if len(pricing.Tiers) != 2 || pricing.Tiers[10].Amount != 5000 {
+		t.Error("failed to parse tiers")
+	}
+	if pricing.Site.Amount != 50000 || pricing.Relicense.Amount != 1000000 {
+		t.Error("failed to parse site and relicense prices")
+	}
+	expected := map[uint]uint{
+		1:    1000,
+		2:    5000,
+		10:   5000,
+		11:   20000,
+		100:  20000,
+		101:  50000,
+		5000: 50000,
+	}
+	for seats, amount := range expected {
+		price, err := pricing.PriceFor(seats)
+		if err != nil {
+			t.Error(err)
+		} else if price.Amount != amount {
+			t.Errorf("priced %d seats at %d, expected %d", seats, price.Amount, amount)
+		}
+	}
+
+	singleOnly := Pricing{Single: Price{Amount: 1000, Currency: "USD"}}
+	if _, err := singleOnly.PriceFor(3); err == nil || err.Error() != "no price for 3 seats" {
+		t.Errorf("priced 3 seats with only a single price: %v", err)
+	}
+	tiered := Pricing{
+		Single: Price{Amount: 1000, Currency: "USD"},
+		Tiers:  map[uint]Price{10: {Amount: 5000, Currency: "USD"}},
+	}
+	if _, err := tiered.PriceFor(11); err == nil || err.Error() != "no price for 11 seats" {
+		t.Errorf("priced 11 seats beyond every tier: %v", err)
+	}
+}
+
+func TestPricingTierPattern(t *testing.T) {
+	bytes := []byte(`{
+	"url": "http://example.com",
+	"licensorID": "d56ee0a6-4ed3-4793-9485-6135644c158f",
+	"pricing": {
+		"10": {"currency": "USD"}
+	}
+}`)
+	var unstructured interface{}
+	err := json.Unmarshal(bytes, &unstructured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOffer(unstructured); err == nil {
+		t.Error("accepted invalid tier price")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+)
+
+// lineItem is a license to buy for an item.
+type lineItem struct {
+	Item      Item
+	Seats     uint
+	Relicense bool
+	Price     Price
+}
+
+// unpricedItem is an item that can't be bought as requested.
+type unpricedItem struct {
+	Item   Item
+	Reason error
+}
+
+// priceItems prices licenses for a team, or relicensing, for items.
+func priceItems(items []Item, seats uint, relicense bool) (lines []lineItem, unpriced []unpricedItem) {
+	for _, item := range items {
+		line := lineItem{Item: item, Seats: seats, Relicense: relicense}
+		if relicense {
+			price := item.Offer.Pricing.Relicense
+			if price.Currency == "" {
+				unpriced = append(unpriced, unpricedItem{
+					Item:   item,
+					Reason: errors.New("not offered for relicensing"),
+				})
+				continue
+			}
+			line.Price = price
+		} else {
+			price, err := item.Offer.Pricing.PriceFor(seats)
+			if err != nil {
+				unpriced = append(unpriced, unpricedItem{Item: item, Reason: err})
+				continue
+			}
+			line.Price = price
+		}
+		lines = append(lines, line)
+	}
+	return
+}
+
+// totalPrices sums the prices of line items, by currency.
+func totalPrices(lines []lineItem) ([]Price, error) {
+	totals := Totals{}
+	for _, line := range lines {
+		err := totals.Add(line.Price)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return totals.Prices(), nil
+}
@@ -1,5 +1,14 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
 // Price represents a price in a specific currency.
 type Price struct {
 	Amount   uint   `json:"amount"`
@@ -35,3 +44,75 @@ const price1_0_0PreSchema = `{
     }
   }
 }`
+
+var priceFlagPattern = regexp.MustCompile(`^([0-9]+)([A-Z]{3})$`)
+
+// parsePrice parses prices written like 1000USD, in minor units of
+// currency followed by a currency code.
+func parsePrice(input string) (price Price, err error) {
+	match := priceFlagPattern.FindStringSubmatch(input)
+	if match == nil {
+		return price, errors.New("invalid price: " + input)
+	}
+	amount, err := strconv.ParseUint(match[1], 10, 0)
+	if err != nil || amount == 0 {
+		return price, errors.New("invalid price amount: " + match[1])
+	}
+	price.Amount = uint(amount)
+	price.Currency = match[2]
+	return
+}
+
+// String formats a price in major units of its currency, like $10.00
+// or ¥1000. Prices in unknown currencies show minor units.
+func (p Price) String() string {
+	currency, ok := findCurrency(p.Currency)
+	if !ok {
+		return fmt.Sprintf("%d %s (minor units)", p.Amount, p.Currency)
+	}
+	return currency.format(p.Amount)
+}
+
+var errPriceOverflow = errors.New("price too large")
+
+// addPrices adds two prices in the same currency.
+func addPrices(a Price, b Price) (Price, error) {
+	if a.Currency != b.Currency {
+		return Price{}, fmt.Errorf("cannot add %s to %s", b.Currency, a.Currency)
+	}
+	sum, carry := bits.Add(a.Amount, b.Amount, 0)
+	if carry != 0 {
+		return Price{}, errPriceOverflow
+	}
+	return Price{Amount: sum, Currency: a.Currency}, nil
+}
+
+// Totals sums prices separately for each currency.
+type Totals map[string]Price
+
+// Add adds a price to the total for its currency.
+func (t Totals) Add(price Price) error {
+	total, ok := t[price.Currency]
+	if !ok {
+		t[price.Currency] = price
+		return nil
+	}
+	sum, err := addPrices(total, price)
+	if err != nil {
+		return err
+	}
+	t[price.Currency] = sum
+	return nil
+}
+
+// Prices returns the total for each currency, ordered by currency
+// code.
+func (t Totals) Prices() (prices []Price) {
+	for _, total := range t {
+		prices = append(prices, total)
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Currency < prices[j].Currency
+	})
+	return
+}
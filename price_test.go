@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPriceString(t *testing.T) {
+	vectors := map[Price]string{
+		{Amount: 1000, Currency: "USD"}: "$10.00",
+		{Amount: 5, Currency: "EUR"}:    "€0.05",
+		{Amount: 1000, Currency: "JPY"}: "¥1000",
+		{Amount: 1234, Currency: "KWD"}: "KWD 1.234",
+		{Amount: 150, Currency: "CHF"}:  "CHF 1.50",
+		{Amount: 100, Currency: "XYZ"}:  "100 XYZ (minor units)",
+	}
+	for price, expected := range vectors {
+		if got := price.String(); got != expected {
+			t.Errorf("%d %s: expected %q, got %q", price.Amount, price.Currency, expected, got)
+		}
+	}
+}
+
+func TestCurrencySchema(t *testing.T) {
+	for _, currency := range []string{"USD", "JPY", "EUR"} {
+		if !validV1Offer(map[string]interface{}{
+			"licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+			"url":        "https://example.com",
+			"pricing": map[string]interface{}{
+				"single": map[string]interface{}{"currency": currency, "amount": 100.0},
+			},
+		}) {
+			t.Errorf("rejected %s", currency)
+		}
+	}
+	if validV1Offer(map[string]interface{}{
+		"licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+		"url":        "https://example.com",
+		"pricing": map[string]interface{}{
+			"single": map[string]interface{}{"currency": "XYZ", "amount": 100.0},
+		},
+	}) {
+		t.Error("accepted unknown currency")
+	}
+}
+
+func TestTotals(t *testing.T) {
+	totals := Totals{}
+	for _, price := range []Price{
+		{Amount: 1000, Currency: "USD"},
+		{Amount: 500, Currency: "EUR"},
+		{Amount: 250, Currency: "USD"},
+	} {
+		if err := totals.Add(price); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prices := totals.Prices()
+	if len(prices) != 2 ||
+		prices[0] != (Price{Amount: 500, Currency: "EUR"}) ||
+		prices[1] != (Price{Amount: 1250, Currency: "USD"}) {
+		t.Errorf("unexpected totals: %v", prices)
+	}
+	if totals.Add(Price{Amount: ^uint(0), Currency: "USD"}) == nil {
+		t.Error("did not detect overflow")
+	}
+	if _, err := addPrices(Price{Amount: 1, Currency: "USD"}, Price{Amount: 1, Currency: "EUR"}); err == nil {
+		t.Error("added prices in different currencies")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+const quoteUsage = `Quote the cost of licenses for artifacts in the current directory.
+
+Usage:
+  licensezero quote [flags]
+
+Flags:
+  --seats N                 number of people who need licenses (default 1)
+  --relicense               quote relicensing instead of licenses
+  --ignore-noncommercial    skip artifacts under noncommercial licenses
+  --ignore-reciprocal       skip artifacts under reciprocal licenses
+  --strict                  treat problems reading files or offers as errors
+  --convert-to CODE         also show prices in another currency
+  --rates FILE              exchange rates for --convert-to
+
+The rates file gives the value of one unit of a base currency in
+other currencies, as of a date:
+
+  {
+    "date": "2026-10-01",
+    "base": "EUR",
+    "rates": {"USD": 1.08, "GBP": 0.86}
+  }
+
+Quotes never fetch exchange rates from the network.
+
+Offers come only from HTTPS APIs listed under "apis" in config.json
+in the configuration directory, by default https://api.licensezero.com.
+Offers are checked against API public keys listed under "publicKeys".
+Set "requireSignedOffers" to refuse offers without valid signatures.
+`
+
+var quoteCommand = subcommand{
+	Summary: "Quote the cost of missing licenses.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("quote", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, quoteUsage) }
+		seats := flagSet.Uint("seats", 1, "")
+		relicense := flagSet.Bool("relicense", false, "")
+		ignoreNoncommercial := flagSet.Bool("ignore-noncommercial", false, "")
+		ignoreReciprocal := flagSet.Bool("ignore-reciprocal", false, "")
+		strict := flagSet.Bool("strict", false, "")
+		convertTo := flagSet.String("convert-to", "", "")
+		ratesFile := flagSet.String("rates", "", "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 || *seats == 0 ||
+			(*convertTo == "") != (*ratesFile == "") {
+			flagSet.Usage()
+			return 1
+		}
+		var conversion *quoteConversion
+		if *convertTo != "" {
+			if _, ok := findCurrency(*convertTo); !ok {
+				fmt.Fprintln(env.Stderr, "Unknown currency:", *convertTo)
+				return 1
+			}
+			rates, err := readExchangeRates(*ratesFile)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not read exchange rates:", err)
+				return 1
+			}
+			conversion = &quoteConversion{Rates: rates, To: *convertTo}
+		}
+		if err := env.setUpNetwork(); err != nil {
+			fmt.Fprintln(env.Stderr, "Could not configure network access:", err)
+			return 1
+		}
+		inventory, err := CompileInventory(env.Config, env.CWD, *ignoreNoncommercial, *ignoreReciprocal)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read dependencies:", err)
+			return 1
+		}
+		if reportDiagnostics(env.Stderr, inventory, *strict) {
+			return 1
+		}
+		err = printQuote(env.Stdout, inventory, *seats, *relicense, conversion)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not quote:", err)
+			return 1
+		}
+		return 0
+	},
+}
+
+// quoteConversion converts quoted prices to a single currency.
+type quoteConversion struct {
+	Rates *exchangeRates
+	To    string
+}
+
+// describe formats a price, followed by its converted amount.
+func (c *quoteConversion) describe(price Price) (string, error) {
+	if c == nil || price.Currency == c.To {
+		return price.String(), nil
+	}
+	converted, err := c.Rates.Convert(price, c.To)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%s)", price, converted), nil
+}
+
+func printQuote(stdout io.Writer, inventory *Inventory, seats uint, relicense bool, conversion *quoteConversion) error {
+	for _, disallowed := range inventory.Disallowed {
+		fmt.Fprintf(stdout, "%s: refused: %v\n", itemName(&disallowed.Item), disallowed.Reason)
+	}
+	for _, item := range inventory.Legacy {
+		fmt.Fprintf(stdout, "%s: not quoted: legacy receipt for offer %s; run receipts sync to replace it\n", itemName(&item), item.OfferID)
+	}
+	if len(inventory.Unlicensed) == 0 {
+		fmt.Fprintln(stdout, "No licenses to buy.")
+		return nil
+	}
+	lines, unpriced := priceItems(inventory.Unlicensed, seats, relicense)
+	for _, line := range lines {
+		description, err := conversion.describe(line.Price)
+		if err != nil {
+			return err
+		}
+		if line.Relicense {
+			fmt.Fprintf(stdout, "%s: %s to relicense\n", itemName(&line.Item), description)
+		} else {
+			fmt.Fprintf(stdout, "%s: %s\n", itemName(&line.Item), description)
+		}
+	}
+	for _, item := range unpriced {
+		fmt.Fprintf(stdout, "%s: %v\n", itemName(&item.Item), item.Reason)
+	}
+	for _, item := range inventory.Unlicensed {
+		if item.Unverified != nil {
+			fmt.Fprintf(stdout, "Warning: %s: %v\n", itemName(&item), item.Unverified)
+		}
+	}
+	totals, err := totalPrices(lines)
+	if err != nil {
+		return err
+	}
+	for _, total := range totals {
+		description, err := conversion.describe(total)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Total: %s\n", description)
+	}
+	if conversion != nil && len(totals) != 0 {
+		converted := Totals{}
+		for _, total := range totals {
+			price, err := conversion.Rates.Convert(total, conversion.To)
+			if err != nil {
+				return err
+			}
+			err = converted.Add(price)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(stdout, "Total in %s: %s\n", conversion.To, converted[conversion.To])
+		fmt.Fprintf(stdout, "Converted at rates as of %s.\n", conversion.Rates.Date)
+	}
+	return nil
+}
+
+// itemName describes an item for humans.
+func itemName(item *Item) string {
+	name := item.Name
+	if item.Scope != "" {
+		name = "@" + item.Scope + "/" + name
+	}
+	if name == "" {
+		name = item.Path
+	}
+	if item.Version != "" {
+		name += "@" + item.Version
+	}
+	return name
+}
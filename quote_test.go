@@ -0,0 +1,216 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestQuoteSeats(t *testing.T) {
+	offerID := "9aab7058-599a-43db-9449-5fc0971ecbfa"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/offers/"+offerID {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+  "url": "https://example.com",
+  "licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+  "pricing": {
+    "single": {"currency": "USD", "amount": 1000},
+    "10": {"currency": "USD", "amount": 5000},
+    "relicense": {"currency": "USD", "amount": 1000000}
+  }
+}`))
+	}))
+	defer server.Close()
+	withAPIClient(server.Client(), func() {
+		WithTestDir(t, func(directory string) {
+			allowTestAPI(t, directory, server.URL)
+			dependency := path.Join(directory, "project", "node_modules", "dependency")
+			err := writeTestArtifact(dependency, server.URL, offerID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			env.CWD = path.Join(directory, "project")
+			if run([]string{"quote", "--seats", "5"}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "Total: $50.00") {
+				t.Errorf("quoted:\n%s", stdout.String())
+			}
+
+			env, stdout, _ = newTestEnvironment(directory, "", nil)
+			env.CWD = path.Join(directory, "project")
+			if run([]string{"quote"}, env) != 0 {
+				t.Fatal("could not quote")
+			}
+			if !strings.Contains(stdout.String(), "Total: $10.00") {
+				t.Errorf("quoted:\n%s", stdout.String())
+			}
+
+			env, stdout, _ = newTestEnvironment(directory, "", nil)
+			env.CWD = path.Join(directory, "project")
+			if run([]string{"quote", "--relicense"}, env) != 0 {
+				t.Fatal("could not quote relicensing")
+			}
+			if !strings.Contains(stdout.String(), "$10000.00 to relicense") ||
+				!strings.Contains(stdout.String(), "Total: $10000.00") {
+				t.Errorf("quoted:\n%s", stdout.String())
+			}
+		})
+	})
+}
+
+func TestQuoteConvert(t *testing.T) {
+	prices := map[string]string{
+		"9aab7058-599a-43db-9449-5fc0971ecbfa": `{"currency": "USD", "amount": 1080}`,
+		"2c743a84-09ce-4549-9f0d-19d8f53462bb": `{"currency": "GBP", "amount": 860}`,
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		price, ok := prices[strings.TrimPrefix(r.URL.Path, "/offers/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+  "url": "https://example.com",
+  "licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+  "pricing": {"single": ` + price + `}
+}`))
+	}))
+	defer server.Close()
+	withAPIClient(server.Client(), func() {
+		WithTestDir(t, func(directory string) {
+			allowTestAPI(t, directory, server.URL)
+			for offerID := range prices {
+				dependency := path.Join(directory, "project", "node_modules", offerID)
+				err := writeTestArtifact(dependency, server.URL, offerID)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			rates := path.Join(directory, "rates.json")
+			err := ioutil.WriteFile(rates, []byte(`{
+  "date": "2026-10-01",
+  "base": "EUR",
+  "rates": {"USD": 1.08, "GBP": 0.86}
+}`), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			env.CWD = path.Join(directory, "project")
+			if run([]string{"quote", "--convert-to", "EUR", "--rates", rates}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			for _, expected := range []string{
+				"Total: $10.80 (€10.00)",
+				"Total: £8.60 (€10.00)",
+				"Total in EUR: €20.00",
+				"as of 2026-10-01",
+			} {
+				if !strings.Contains(stdout.String(), expected) {
+					t.Errorf("missing %q in quote:\n%s", expected, stdout.String())
+				}
+			}
+
+			env, _, _ = newTestEnvironment(directory, "", nil)
+			env.CWD = path.Join(directory, "project")
+			if run([]string{"quote", "--convert-to", "EUR"}, env) == 0 {
+				t.Error("converted without rates")
+			}
+		})
+	})
+}
+
+func TestQuoteStrict(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		writeTestFiles(t, directory, map[string]string{
+			"node_modules/broken/licensezero.json": "{",
+		})
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"quote"}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		if !strings.Contains(stderr.String(), "Warning: parser: "+path.Join(directory, "node_modules", "broken", "licensezero.json")) ||
+			!strings.Contains(stderr.String(), "1 warning. Use --strict") {
+			t.Errorf("stderr:\n%s", stderr.String())
+		}
+
+		env, _, stderr = newTestEnvironment(directory, "", nil)
+		if run([]string{"quote", "--strict"}, env) == 0 {
+			t.Fatal("quoted despite a broken file")
+		}
+		if !strings.Contains(stderr.String(), "Error: parser: ") || !strings.Contains(stderr.String(), "1 error.") {
+			t.Errorf("strict stderr:\n%s", stderr.String())
+		}
+	})
+}
+
+func TestQuoteLegacyReceipt(t *testing.T) {
+	offerID := "9aab7058-599a-43db-9449-5fc0971ecbfa"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/offers/"+offerID {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+  "url": "https://example.com",
+  "licensorID": "59e70a4d-ffee-4e9d-a526-7a9ff9161664",
+  "pricing": {"single": {"currency": "USD", "amount": 1000}}
+}`))
+	}))
+	defer server.Close()
+	withAPIClient(server.Client(), func() {
+		WithTestDir(t, func(directory string) {
+			allowTestAPI(t, directory, server.URL)
+			writeTestFiles(t, directory, map[string]string{
+				"licenses/" + offerID + ".json": `{
+  "manifest": "` + testLegacyManifest + `",
+  "document": "Legacy license text.",
+  "publicKey": "` + strings.Repeat("a", 64) + `",
+  "signature": "` + strings.Repeat("b", 128) + `"
+}`,
+			})
+			env, _, stderr := newTestEnvironment(directory, "", nil)
+			if run([]string{"migrate", "--api", server.URL}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			err := writeTestArtifact(path.Join(directory, "project"), server.URL, offerID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			env.CWD = path.Join(directory, "project")
+			if run([]string{"quote"}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "not quoted: legacy receipt for offer "+offerID) ||
+				!strings.Contains(stdout.String(), "No licenses to buy.") {
+				t.Errorf("quoted:\n%s", stdout.String())
+			}
+		})
+	})
+}
+
+func writeTestArtifact(directory string, api string, offerID string) error {
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(directory, "licensezero.json"), []byte(`{
+  "offers": [
+    {
+      "api": "`+api+`",
+      "offerID": "`+offerID+`",
+      "public": "Parity-7.0.0"
+    }
+  ]
+}`), 0644)
+}
@@ -1,9 +1,7 @@
 package main
 
 import (
-	"bytes"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"github.com/mitchellh/mapstructure"
 	"github.com/xeipuuv/gojsonschema"
@@ -22,26 +20,32 @@ type Receipt interface {
 	Licensee() Licensee
 	Vendor() Vendor
 	Form() string
+	Relicense() bool
 	ValidateSignature() error
 }
 
 type receipt1_0_0Pre struct {
-	Key       string
-	Signature string
-	License   struct {
-		Values struct {
-			API       string
-			OfferID   string
-			OrderID   string
-			Effective string
-			Price     Price
-			Expires   string
-			Licensor  Licensor
-			Licensee  Licensee
-			Vendor    Vendor
-		}
-		Form string
-	}
+	Key       string          `json:"key"`
+	Signature string          `json:"signature"`
+	License   license1_0_0Pre `json:"license"`
+}
+
+type license1_0_0Pre struct {
+	Form   string                `json:"form"`
+	Values licenseValues1_0_0Pre `json:"values"`
+}
+
+type licenseValues1_0_0Pre struct {
+	API       string   `json:"api"`
+	OfferID   string   `json:"offerID"`
+	OrderID   string   `json:"orderID"`
+	Effective string   `json:"effective"`
+	Price     *Price   `json:"price,omitempty"`
+	Expires   string   `json:"expires,omitempty"`
+	Licensor  Licensor `json:"licensor"`
+	Licensee  Licensee `json:"licensee"`
+	Vendor    *Vendor  `json:"vendor,omitempty"`
+	Relicense bool     `json:"relicense,omitempty"`
 }
 
 func (r receipt1_0_0Pre) API() string {
@@ -65,7 +69,10 @@ func (r receipt1_0_0Pre) Expires() string {
 }
 
 func (r receipt1_0_0Pre) Price() Price {
-	return r.License.Values.Price
+	if price := r.License.Values.Price; price != nil {
+		return *price
+	}
+	return Price{}
 }
 
 func (r receipt1_0_0Pre) Licensor() Licensor {
@@ -77,87 +84,26 @@ func (r receipt1_0_0Pre) Licensee() Licensee {
 }
 
 func (r receipt1_0_0Pre) Vendor() Vendor {
-	return r.License.Values.Vendor
+	if vendor := r.License.Values.Vendor; vendor != nil {
+		return *vendor
+	}
+	return Vendor{}
 }
 
 func (r receipt1_0_0Pre) Form() string {
 	return r.License.Form
 }
 
-func (r receipt1_0_0Pre) ValidateSignature() error {
-	serialized := serializeV1License(&r)
-	return checkSignature(r.Key, r.Signature, serialized)
+func (r receipt1_0_0Pre) Relicense() bool {
+	return r.License.Values.Relicense
 }
 
-// Manually implement JSON serialization for receipt license objects
-// to ensure that keys are serialized in sorted order and optional
-// properties, like price, get correctly omitted.
-func serializeV1License(r *receipt1_0_0Pre) []byte {
-	var buffer bytes.Buffer
-	buffer.WriteString("{")
-	buffer.WriteString("\"form\":" + stringify(r.License.Form) + ",")
-	values := r.License.Values
-
-	// Values
-	buffer.WriteString("\"values\":{")
-	buffer.WriteString("\"api\":" + stringify(values.API) + ",")
-	buffer.WriteString("\"effective\":" + stringify(values.Effective) + ",")
-	if expires := values.Expires; expires != "" {
-		buffer.WriteString("\"expires\":" + stringify(expires) + ",")
-	}
-
-	// Licensee
-	licensee := values.Licensee
-	buffer.WriteString("\"licensee\":{")
-	buffer.WriteString("\"email\":" + stringify(licensee.EMail) + ",")
-	buffer.WriteString("\"jurisdiction\":" + stringify(licensee.Jurisdiction) + ",")
-	buffer.WriteString("\"name\":" + stringify(licensee.Name))
-	buffer.WriteString("},")
-
-	// Licensor
-	licensor := values.Licensor
-	buffer.WriteString("\"licensor\":{")
-	buffer.WriteString("\"email\":" + stringify(licensor.EMail) + ",")
-	buffer.WriteString("\"jurisdiction\":" + stringify(licensor.Jurisdiction) + ",")
-	buffer.WriteString("\"licensorID\":" + stringify(licensor.LicensorID) + ",")
-	buffer.WriteString("\"name\":" + stringify(licensor.Name))
-	buffer.WriteString("},")
-
-	// OfferID
-	buffer.WriteString("\"offerID\":" + stringify(values.OfferID) + ",")
-
-	// OrderID
-	buffer.WriteString("\"orderID\":" + stringify(values.OrderID))
-
-	// Price
-	if price := values.Price; price.Currency != "" && price.Amount != 0 {
-		buffer.WriteString(",")
-		buffer.WriteString("\"price\":{")
-		buffer.WriteString("\"amount\":" + stringify(price.Amount) + ",")
-		buffer.WriteString("\"currency\":" + stringify(price.Currency))
-		buffer.WriteString("}")
-	}
-
-	// Vendor
-	if vendor := values.Vendor; vendor.Name != "" {
-		buffer.WriteString(",")
-		buffer.WriteString("\"vendor\":{")
-		buffer.WriteString("\"email\":" + stringify(vendor.EMail) + ",")
-		buffer.WriteString("\"jurisdiction\":" + stringify(vendor.Jurisdiction) + ",")
-		buffer.WriteString("\"name\":" + stringify(vendor.Name) + ",")
-		buffer.WriteString("\"website\":" + stringify(vendor.Website))
-		buffer.WriteString("}")
+func (r receipt1_0_0Pre) ValidateSignature() error {
+	serialized, err := canonicalJSON(r.License)
+	if err != nil {
+		return err
 	}
-
-	buffer.WriteString("}") // values
-	buffer.WriteString("}") // object
-
-	return buffer.Bytes()
-}
-
-func stringify(input interface{}) (result string) {
-	data, _ := json.Marshal(input)
-	return string(data)
+	return checkSignature(r.Key, r.Signature, serialized)
 }
 
 func checkSignature(publicKey string, signature string, json []byte) error {
@@ -250,6 +196,11 @@ const receipt1_0_0PreSchema = `{
               "title": "purchase price",
               "$ref": "price.json"
             },
+            "relicense": {
+              "title": "relicense",
+              "comment": "A relicense covers everyone, not just the licensee.",
+              "type": "boolean"
+            },
             "licensee": {
               "title": "licensee",
               "comment": "The licensee is the one receiving the license.",
@@ -307,7 +258,7 @@ const receipt1_0_0PreSchema = `{
               }
             },
             "vendor": {
-              "title": "licesne vendor",
+              "title": "license vendor",
               "comment": "information on the party that sold the license, such as an agent or reseller, if the licensor did not sell the license themself",
               "type": "object",
               "required": [
@@ -349,33 +300,19 @@ const receipt1_0_0PreSchema = `{
 
 // ParseReceipt validates and parses parsed JSON data as a Receipt.
 func ParseReceipt(unstructured interface{}) (Receipt, error) {
-	if validV1Receipt(unstructured) {
-		return parseV1Receipt(unstructured), nil
-	}
-	return nil, errors.New("unknown schema")
-}
-
-var v1ReceiptSchema *gojsonschema.Schema = nil
-
-func validV1Receipt(parsed interface{}) bool {
-	if v1ReceiptSchema == nil {
-		schema, err := schemaLoader().Compile(
-			gojsonschema.NewStringLoader(receipt1_0_0PreSchema),
-		)
-		if err != nil {
-			panic(err)
-		}
-		v1ReceiptSchema = schema
-	}
-	dataLoader := gojsonschema.NewGoLoader(parsed)
-	result, err := v1ReceiptSchema.Validate(dataLoader)
+	version, data, err := detectVersion("receipt", unstructured)
 	if err != nil {
-		return false
+		return nil, err
 	}
-	if result.Valid() {
-		return true
+	switch version {
+	case "1.0.0-pre":
+		return parseV1Receipt(data), nil
 	}
-	return false
+	return nil, errUnknownSchema
+}
+
+func validateV1Receipt(parsed interface{}) (*gojsonschema.Result, error) {
+	return schemas.validate(schemaID("1.0.0-pre", "receipt"), parsed)
 }
 
 func parseV1Receipt(unstructured interface{}) (r receipt1_0_0Pre) {
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+)
+
+var receiptsSubcommands = map[string]subcommand{
+	"render": {
+		Summary: "Render a receipt as a license document.",
+		Handler: receiptsRender,
+	},
+	"sync": {
+		Summary: "Fetch your receipts from licensing APIs.",
+		Handler: receiptsSync,
+	},
+}
+
+var receiptsCommand = subcommand{
+	Summary: "Work with license receipts.",
+	Handler: func(args []string, env *environment) int {
+		return dispatch("receipts", receiptsSubcommands, args, env)
+	},
+}
+
+// parseReceiptData parses a receipt, checking it against the schema
+// and verifying its signature.
+func parseReceiptData(data []byte) (Receipt, error) {
+	var unstructured interface{}
+	err := json.Unmarshal(data, &unstructured)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := ParseReceipt(unstructured)
+	if err != nil {
+		return nil, err
+	}
+	err = receipt.ValidateSignature()
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// checkReceiptKey checks that a receipt was signed with the public key
+// configured for its API, if there is one.
+func checkReceiptKey(receipt Receipt, api string, config *configFile) error {
+	publicKey, ok := config.publicKeyFor(api)
+	if !ok {
+		return nil
+	}
+	if v1, isV1 := receipt.(receipt1_0_0Pre); !isV1 || v1.Key != publicKey {
+		return errors.New("receipt for order " + receipt.OrderID() + " not signed with the API's key")
+	}
+	return nil
+}
+
+func receiptPath(configPath string, receipt Receipt) string {
+	return receiptPathIn(path.Join(configPath, "receipts"), receipt)
+}
+
+func receiptPathIn(directory string, receipt Receipt) string {
+	return path.Join(directory, receipt.OrderID()+"-"+receipt.OfferID()+".json")
+}
+
+// saveReceipt adds a receipt to the receipts directory, reporting
+// false if it was already there.
+func saveReceipt(configPath string, receipt Receipt, data []byte) (bool, error) {
+	return saveReceiptIn(path.Join(configPath, "receipts"), receipt, data)
+}
+
+// saveReceiptIn saves a receipt in a directory, reporting false if it
+// was already there.
+func saveReceiptIn(directory string, receipt Receipt, data []byte) (bool, error) {
+	filePath := receiptPathIn(directory, receipt)
+	if _, err := os.Stat(filePath); err == nil {
+		return false, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+	err := os.MkdirAll(directory, 0700)
+	if err != nil {
+		return false, err
+	}
+	err = writeFileAtomically(filePath, data, 0600)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const receiptsSyncUsage = `Fetch your receipts from licensing APIs.
+
+Usage:
+  licensezero receipts sync [--api URL] [<orderID>...]
+
+Without order IDs, asks for receipts for each of your identities.
+Asks each API listed in config.json, or just the one given with --api.
+Receipts must have valid signatures, and must be signed with the API's
+key if config.json lists one under "publicKeys".
+`
+
+func receiptsSync(args []string, env *environment) int {
+	flagSet := flag.NewFlagSet("receipts sync", flag.ContinueOnError)
+	flagSet.SetOutput(env.Stderr)
+	flagSet.Usage = func() { fmt.Fprint(env.Stderr, receiptsSyncUsage) }
+	api := flagSet.String("api", "", "")
+	orderIDs, err := parseInterspersed(flagSet, args)
+	if err != nil {
+		flagSet.Usage()
+		return 1
+	}
+	config, err := readConfigFile(env.Config)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "Could not read configuration:", err)
+		return 1
+	}
+	apis := config.APIs
+	if *api != "" {
+		trimmed := strings.TrimRight(*api, "/")
+		if err := config.checkAPIAllowed(trimmed); err != nil {
+			fmt.Fprintln(env.Stderr, err)
+			return 1
+		}
+		apis = []string{trimmed}
+	}
+	var queries []url.Values
+	if len(orderIDs) != 0 {
+		for _, orderID := range orderIDs {
+			if !validUUID.MatchString(orderID) {
+				fmt.Fprintln(env.Stderr, "Invalid order ID:", orderID)
+				return 1
+			}
+			queries = append(queries, url.Values{"order": {orderID}})
+		}
+	} else {
+		identities, _, err := ReadIdentities(env.Config)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read identities:", err)
+			return 1
+		}
+		if len(identities) == 0 {
+			fmt.Fprintln(env.Stderr, "No identities. Configure one with licensezero identify, or give order IDs.")
+			return 1
+		}
+		for _, identity := range identities {
+			queries = append(queries, url.Values{"email": {identity.EMail}})
+		}
+	}
+
+	if err := env.setUpNetwork(); err != nil {
+		fmt.Fprintln(env.Stderr, "Could not configure network access:", err)
+		return 1
+	}
+	failed := false
+	added, present, rejected := 0, 0, 0
+	for _, api := range apis {
+		for _, query := range queries {
+			receipts, err := GetReceipts(api, query)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not fetch receipts from "+api+":", err)
+				failed = true
+				continue
+			}
+			for _, data := range receipts {
+				receipt, err := parseReceiptData(data)
+				if err == nil {
+					err = checkSyncedReceipt(receipt, api, query, config)
+				}
+				if err != nil {
+					fmt.Fprintln(env.Stderr, "Rejected receipt from "+api+":", err)
+					rejected++
+					continue
+				}
+				saved, err := saveReceipt(env.Config, receipt, data)
+				if err != nil {
+					fmt.Fprintln(env.Stderr, "Could not save receipt:", err)
+					failed = true
+					continue
+				}
+				if saved {
+					fmt.Fprintf(env.Stdout, "Added order %s, offer %s\n", receipt.OrderID(), receipt.OfferID())
+					added++
+				} else {
+					present++
+				}
+			}
+		}
+	}
+	fmt.Fprintf(env.Stdout, "%d added, %d already present, %d rejected.\n", added, present, rejected)
+	if failed || rejected != 0 {
+		return 1
+	}
+	return 0
+}
+
+// checkSyncedReceipt checks that a receipt from an API answers the
+// query it was fetched with, and is signed with the API's key, if one
+// is configured.
+func checkSyncedReceipt(receipt Receipt, api string, query url.Values, config *configFile) error {
+	if strings.TrimRight(receipt.API(), "/") != api {
+		return errors.New("receipt from " + receipt.API() + " instead of " + api)
+	}
+	if orderID := query.Get("order"); orderID != "" && receipt.OrderID() != orderID {
+		return errors.New("receipt for order " + receipt.OrderID() + " instead of " + orderID)
+	}
+	if email := query.Get("email"); email != "" && !strings.EqualFold(receipt.Licensee().EMail, email) {
+		return errors.New("receipt for " + receipt.Licensee().EMail + " instead of " + email)
+	}
+	return checkReceiptKey(receipt, api, config)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"licensezero.com/cli2/devserver"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReceiptsSync(t *testing.T) {
+	withDevServer(t, func(server *devserver.Server) {
+		server.AutoComplete = true
+	}, func(server *httptest.Server) {
+		WithTestDir(t, func(directory string) {
+			allowTestAPI(t, directory, server.URL)
+			identity := testLicense().Values.Licensee
+			err := writeIdentity(directory, &identity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			order, err := CreateOrder(server.URL, OrderRequest{
+				Licensee: identity,
+				Offers: []string{
+					"9aab7058-599a-43db-9449-5fc0971ecbfa",
+					"d56ee0a6-4ed3-4793-9485-6135644c158f",
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			if run([]string{"receipts", "sync", order.OrderID}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "2 added, 0 already present, 0 rejected.") {
+				t.Errorf("first sync output:\n%s", stdout.String())
+			}
+			receipts, errors, err := ReadReceipts(directory)
+			if err != nil || len(errors) != 0 || len(receipts) != 2 {
+				t.Fatalf("read %d receipts, errors %v %v", len(receipts), errors, err)
+			}
+
+			env, stdout, stderr = newTestEnvironment(directory, "", nil)
+			if run([]string{"receipts", "sync"}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "0 added, 2 already present, 0 rejected.") {
+				t.Errorf("second sync output:\n%s", stdout.String())
+			}
+		})
+	})
+}
+
+func TestReceiptsSyncTrailingSlash(t *testing.T) {
+	withDevServer(t, func(server *devserver.Server) {
+		server.AutoComplete = true
+	}, func(server *httptest.Server) {
+		WithTestDir(t, func(directory string) {
+			allowTestAPI(t, directory, server.URL+"/")
+			order, err := CreateOrder(server.URL, OrderRequest{
+				Licensee: testLicense().Values.Licensee,
+				Offers:   []string{"9aab7058-599a-43db-9449-5fc0971ecbfa"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			if run([]string{"receipts", "sync", order.OrderID}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			if !strings.Contains(stdout.String(), "1 added, 0 already present, 0 rejected.") {
+				t.Errorf("output:\n%s", stdout.String())
+			}
+		})
+	})
+}
+
+func TestReceiptsSyncDisallowed(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"receipts", "sync", "--api", "https://example.com"}, env) == 0 {
+			t.Fatal("synced from an unlisted API")
+		}
+		if !strings.Contains(stderr.String(), "example.com") {
+			t.Errorf("stderr:\n%s", stderr.String())
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"strings"
+	textTemplate "text/template"
+)
+
+// receiptDocument holds the values for a rendered license document.
+type receiptDocument struct {
+	OrderID              string
+	OfferID              string
+	API                  string
+	Effective            string
+	Expires              string
+	Price                string
+	Licensee             Licensee
+	Licensor             Licensor
+	Vendor               *Vendor
+	Key                  string
+	SignatureFingerprint string
+	SignatureValid       bool
+	Relicense            bool
+	Form                 string
+	Paragraphs           []string
+}
+
+func newReceiptDocument(receipt Receipt) (*receiptDocument, error) {
+	v1, ok := receipt.(receipt1_0_0Pre)
+	if !ok {
+		return nil, errors.New("unsupported receipt version")
+	}
+	document := receiptDocument{
+		OrderID:              receipt.OrderID(),
+		OfferID:              receipt.OfferID(),
+		API:                  receipt.API(),
+		Effective:            receipt.Effective(),
+		Expires:              receipt.Expires(),
+		Licensee:             receipt.Licensee(),
+		Licensor:             receipt.Licensor(),
+		Key:                  v1.Key,
+		SignatureFingerprint: fingerprint(v1.Signature),
+		SignatureValid:       receipt.ValidateSignature() == nil,
+		Relicense:            receipt.Relicense(),
+		Form:                 strings.TrimSpace(receipt.Form()),
+	}
+	if price := receipt.Price(); price.Currency != "" {
+		document.Price = price.String()
+	}
+	if vendor := receipt.Vendor(); vendor.Name != "" {
+		document.Vendor = &vendor
+	}
+	for _, paragraph := range strings.Split(document.Form, "\n\n") {
+		if trimmed := strings.TrimSpace(paragraph); trimmed != "" {
+			document.Paragraphs = append(document.Paragraphs, trimmed)
+		}
+	}
+	return &document, nil
+}
+
+// fingerprint returns a short, colon-separated SHA-256 digest of a
+// hex-encoded signature, for comparing printed copies of receipts.
+func fingerprint(signature string) string {
+	digest := sha256.Sum256([]byte(signature))
+	encoded := hex.EncodeToString(digest[:16])
+	var groups []string
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, ":")
+}
+
+// markdownEscaper escapes values in Markdown tables, so they can't
+// end cells or add HTML.
+var markdownEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"|", `\|`,
+)
+
+const markdownReceiptTemplate = `# License {{.OrderID}}
+
+| | |
+|---|---|
+| Licensee | {{md .Licensee.Name}} &lt;{{md .Licensee.EMail}}&gt; ({{md .Licensee.Jurisdiction}}) |
+{{- if .Relicense}}
+| Scope | Relicense, covering everyone |
+{{- end}}
+| Licensor | {{md .Licensor.Name}} &lt;{{md .Licensor.EMail}}&gt; ({{md .Licensor.Jurisdiction}}) |
+{{- with .Vendor}}
+| Vendor | {{md .Name}} &lt;{{md .EMail}}&gt; ({{md .Jurisdiction}}), {{md .Website}} |
+{{- end}}
+{{- with .Price}}
+| Price | {{md .}} |
+{{- end}}
+| Effective | {{md .Effective}} |
+{{- with .Expires}}
+| Expires | {{md .}} |
+{{- end}}
+| Offer | {{md .OfferID}} |
+| API | {{md .API}} |
+| Signing Key | ` + "`{{.Key}}`" + ` |
+| Signature Fingerprint | ` + "`{{.SignatureFingerprint}}`" + ` |
+| Signature | {{if .SignatureValid}}valid{{else}}INVALID{{end}} |
+
+## Terms
+{{range .Paragraphs}}
+{{.}}
+{{end}}`
+
+const textReceiptTemplate = `License {{.OrderID}}
+
+Licensee:              {{.Licensee.Name}} <{{.Licensee.EMail}}> ({{.Licensee.Jurisdiction}})
+{{- if .Relicense}}
+Scope:                 Relicense, covering everyone
+{{- end}}
+Licensor:              {{.Licensor.Name}} <{{.Licensor.EMail}}> ({{.Licensor.Jurisdiction}})
+{{- with .Vendor}}
+Vendor:                {{.Name}} <{{.EMail}}> ({{.Jurisdiction}}), {{.Website}}
+{{- end}}
+{{- with .Price}}
+Price:                 {{.}}
+{{- end}}
+Effective:             {{.Effective}}
+{{- with .Expires}}
+Expires:               {{.}}
+{{- end}}
+Offer:                 {{.OfferID}}
+API:                   {{.API}}
+Signing Key:           {{.Key}}
+Signature Fingerprint: {{.SignatureFingerprint}}
+Signature:             {{if .SignatureValid}}valid{{else}}INVALID{{end}}
+
+Terms
+{{range .Paragraphs}}
+{{.}}
+{{end}}`
+
+const htmlReceiptTemplate = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>License {{.OrderID}}</title>
+</head>
+<body>
+<h1>License {{.OrderID}}</h1>
+<table>
+<tr><th>Licensee</th><td>{{.Licensee.Name}} &lt;{{.Licensee.EMail}}&gt; ({{.Licensee.Jurisdiction}})</td></tr>
+{{- if .Relicense}}
+<tr><th>Scope</th><td>Relicense, covering everyone</td></tr>
+{{- end}}
+<tr><th>Licensor</th><td>{{.Licensor.Name}} &lt;{{.Licensor.EMail}}&gt; ({{.Licensor.Jurisdiction}})</td></tr>
+{{- with .Vendor}}
+<tr><th>Vendor</th><td>{{.Name}} &lt;{{.EMail}}&gt; ({{.Jurisdiction}}), <a href="{{.Website}}">{{.Website}}</a></td></tr>
+{{- end}}
+{{- with .Price}}
+<tr><th>Price</th><td>{{.}}</td></tr>
+{{- end}}
+<tr><th>Effective</th><td>{{.Effective}}</td></tr>
+{{- with .Expires}}
+<tr><th>Expires</th><td>{{.}}</td></tr>
+{{- end}}
+<tr><th>Offer</th><td>{{.OfferID}}</td></tr>
+<tr><th>API</th><td>{{.API}}</td></tr>
+<tr><th>Signing Key</th><td><code>{{.Key}}</code></td></tr>
+<tr><th>Signature Fingerprint</th><td><code>{{.SignatureFingerprint}}</code></td></tr>
+<tr><th>Signature</th><td>{{if .SignatureValid}}valid{{else}}INVALID{{end}}</td></tr>
+</table>
+<h2>Terms</h2>
+{{- range .Paragraphs}}
+<p>{{.}}</p>
+{{- end}}
+</body>
+</html>
+`
+
+func renderReceipt(output io.Writer, receipt Receipt, format string) error {
+	document, err := newReceiptDocument(receipt)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "md":
+		return textTemplate.Must(
+			textTemplate.New("md").Funcs(textTemplate.FuncMap{
+				"md": markdownEscaper.Replace,
+			}).Parse(markdownReceiptTemplate),
+		).Execute(output, document)
+	case "txt":
+		return textTemplate.Must(
+			textTemplate.New("txt").Parse(textReceiptTemplate),
+		).Execute(output, document)
+	case "html":
+		return htmlTemplate.Must(
+			htmlTemplate.New("html").Parse(htmlReceiptTemplate),
+		).Execute(output, document)
+	default:
+		return errors.New("unknown format: " + format)
+	}
+}
+
+const receiptsRenderUsage = `Render a receipt as a license document.
+
+Usage:
+  licensezero receipts render <orderID> [<offerID>] [--format md|html|txt]
+
+Orders for more than one offer have a receipt for each. Give the
+offer ID to choose one.
+`
+
+func receiptsRender(args []string, env *environment) int {
+	flagSet := flag.NewFlagSet("receipts render", flag.ContinueOnError)
+	flagSet.SetOutput(env.Stderr)
+	flagSet.Usage = func() { fmt.Fprint(env.Stderr, receiptsRenderUsage) }
+	format := flagSet.String("format", "md", "")
+	positional, err := parseInterspersed(flagSet, args)
+	if err != nil || len(positional) < 1 || len(positional) > 2 {
+		flagSet.Usage()
+		return 1
+	}
+	orderID := positional[0]
+	receipts, _, err := ReadReceipts(env.Config)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "Could not read receipts:", err)
+		return 1
+	}
+	var matches []Receipt
+	for _, receipt := range receipts {
+		if receipt.OrderID() != orderID {
+			continue
+		}
+		if len(positional) == 2 && receipt.OfferID() != positional[1] {
+			continue
+		}
+		matches = append(matches, receipt)
+	}
+	if len(matches) == 0 {
+		if len(positional) == 2 {
+			fmt.Fprintln(env.Stderr, "No receipt for order", orderID, "and offer", positional[1])
+		} else {
+			fmt.Fprintln(env.Stderr, "No receipt for order", orderID)
+		}
+		return 1
+	}
+	if len(matches) > 1 {
+		fmt.Fprintf(env.Stderr, "Order %s has receipts for %d offers. Give one of these offer IDs:\n", orderID, len(matches))
+		for _, receipt := range matches {
+			fmt.Fprintln(env.Stderr, "  "+receipt.OfferID())
+		}
+		return 1
+	}
+	err = renderReceipt(env.Stdout, matches[0], *format)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "Could not render receipt:", err)
+		return 1
+	}
+	return 0
+}
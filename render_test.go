@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderReceipt(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		license := testLicense()
+		license.Values.Licensee.Name = "Ada <Lovelace> & Co"
+		license.Values.Price = &Price{Amount: 1000, Currency: "USD"}
+		license.Values.Vendor = &Vendor{
+			EMail:        "support@artlessdevices.com",
+			Jurisdiction: "US-CA",
+			Name:         "Artless Devices LLC",
+			Website:      "https://artlessdevices.com",
+		}
+		receipt := issueTestReceipt(t, directory, license)
+		orderID := license.Values.OrderID
+
+		for _, format := range []string{"md", "txt", "html"} {
+			env, stdout, stderr := newTestEnvironment(directory, "", nil)
+			if run([]string{"receipts", "render", orderID, "--format", format}, env) != 0 {
+				t.Fatal(stderr.String())
+			}
+			output := stdout.String()
+			for _, expected := range []string{
+				orderID,
+				"Jane Licensor",
+				"Artless Devices LLC",
+				"2018-11-13T20:20:39Z",
+				fingerprint(receipt.Signature),
+				"Second paragraph.",
+				"$10.00",
+				"valid",
+			} {
+				if !strings.Contains(output, expected) {
+					t.Errorf("%s output missing %q", format, expected)
+				}
+			}
+			if format == "html" && strings.Contains(output, "<Lovelace>") {
+				t.Error("did not escape HTML")
+			}
+		}
+	})
+}
+
+func TestRenderMarkdownEscapes(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		license := testLicense()
+		license.Values.Licensee.Name = "Ada <Lovelace> | Co"
+		issueTestReceipt(t, directory, license)
+		env, stdout, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"receipts", "render", license.Values.OrderID}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		output := stdout.String()
+		if !strings.Contains(output, `| Licensee | Ada &lt;Lovelace&gt; \| Co &lt;`) {
+			t.Errorf("did not escape licensee:\n%s", output)
+		}
+		if strings.Contains(output, "<Lovelace>") {
+			t.Error("rendered HTML from the licensee name")
+		}
+	})
+}
+
+func TestRenderMissingReceipt(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"receipts", "render", "nonexistent"}, env) == 0 {
+			t.Error("rendered missing receipt")
+		}
+		if !strings.Contains(stderr.String(), "No receipt") {
+			t.Error("did not report missing receipt")
+		}
+	})
+}
+
+func TestRenderOrderWithTwoOffers(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		first := testLicense()
+		second := testLicense()
+		second.Values.OfferID = "d56ee0a6-4ed3-4793-9485-6135644c158f"
+		second.Form = "Second offer's license form."
+		issueTestReceipt(t, directory, first)
+		issueTestReceipt(t, directory, second)
+		orderID := first.Values.OrderID
+
+		env, _, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"receipts", "render", orderID}, env) == 0 {
+			t.Error("rendered one of two receipts without an offer ID")
+		}
+		for _, offerID := range []string{first.Values.OfferID, second.Values.OfferID} {
+			if !strings.Contains(stderr.String(), offerID) {
+				t.Errorf("did not list offer %s:\n%s", offerID, stderr.String())
+			}
+		}
+
+		env, stdout, stderr := newTestEnvironment(directory, "", nil)
+		if run([]string{"receipts", "render", orderID, second.Values.OfferID}, env) != 0 {
+			t.Fatal(stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "Second offer's license form.") {
+			t.Errorf("rendered the wrong receipt:\n%s", stdout.String())
+		}
+
+		env, _, stderr = newTestEnvironment(directory, "", nil)
+		if run([]string{"receipts", "render", orderID, "00000000-0000-4000-8000-000000000000"}, env) == 0 {
+			t.Error("rendered a receipt for another offer")
+		}
+	})
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var schemaSubcommands = map[string]subcommand{
+	"list": {
+		Summary: "List schemas.",
+		Handler: schemaList,
+	},
+	"get": {
+		Summary: "Print a schema.",
+		Handler: schemaGet,
+	},
+	"docs": {
+		Summary: "Print Markdown reference docs for schemas.",
+		Handler: schemaDocs,
+	},
+}
+
+var schemaCommand = subcommand{
+	Summary: "Print JSON schemas for artifacts, offers, and receipts.",
+	Handler: func(args []string, env *environment) int {
+		return dispatch("schema", schemaSubcommands, args, env)
+	},
+}
+
+const schemaGetUsage = `Print a schema.
+
+Usage:
+  licensezero schema get <name>
+
+Name a schema like "offer" for the newest version, like
+"1.0.0-pre/offer" for a specific version, or by $id.
+`
+
+func schemaList(args []string, env *environment) int {
+	if len(args) != 0 {
+		fmt.Fprintln(env.Stderr, "Usage: licensezero schema list")
+		return 1
+	}
+	for _, id := range schemas.ids() {
+		fmt.Fprintf(env.Stdout, "%-26s %s\n", schemaName(id), schemas.title(id))
+	}
+	return 0
+}
+
+func schemaGet(args []string, env *environment) int {
+	if len(args) != 1 {
+		fmt.Fprint(env.Stderr, schemaGetUsage)
+		return 1
+	}
+	id, ok := schemas.lookup(args[0])
+	if !ok {
+		fmt.Fprintln(env.Stderr, "Unknown schema:", args[0])
+		return 1
+	}
+	fmt.Fprintln(env.Stdout, schemas.sources[id])
+	return 0
+}
+
+func schemaDocs(args []string, env *environment) int {
+	if len(args) != 0 {
+		fmt.Fprintln(env.Stderr, "Usage: licensezero schema docs")
+		return 1
+	}
+	err := writeSchemaDocs(env.Stdout, schemas)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "Could not write docs:", err)
+		return 1
+	}
+	return 0
+}
+
+// ids lists the $id of every schema, sorted.
+func (r *schemaRegistry) ids() (ids []string) {
+	for id := range r.sources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return
+}
+
+// parsed returns the schema with an $id as generic JSON.
+func (r *schemaRegistry) parsed(id string) map[string]interface{} {
+	var object map[string]interface{}
+	json.Unmarshal([]byte(r.sources[id]), &object)
+	return object
+}
+
+func (r *schemaRegistry) title(id string) string {
+	title, _ := r.parsed(id)["title"].(string)
+	return title
+}
+
+// lookup finds a schema by $id, by version and name, or by name alone
+// for the newest version.
+func (r *schemaRegistry) lookup(name string) (string, bool) {
+	name = strings.TrimSuffix(name, ".json")
+	candidates := []string{name + ".json", schemaBase + name + ".json"}
+	for _, version := range schemaVersions {
+		candidates = append(candidates, schemaID(version, name))
+	}
+	for _, candidate := range candidates {
+		if _, ok := r.sources[candidate]; ok {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// unresolvedReferences lists $ref values in schemas that don't name
+// another schema in the registry.
+func (r *schemaRegistry) unresolvedReferences() (unresolved []string) {
+	for _, id := range r.ids() {
+		walkSchema(r.parsed(id), func(node map[string]interface{}) {
+			ref, ok := node["$ref"].(string)
+			if !ok {
+				return
+			}
+			if _, ok := r.sources[resolveReference(id, ref)]; !ok {
+				unresolved = append(unresolved, id+": "+ref)
+			}
+		})
+	}
+	return
+}
+
+// resolveReference returns the $id a $ref in a schema names.
+func resolveReference(id, ref string) string {
+	base, err := url.Parse(id)
+	if err != nil {
+		return ref
+	}
+	target, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	target.Fragment = ""
+	return target.String()
+}
+
+// walkSchema calls a function for every object in a schema.
+func walkSchema(value interface{}, visit func(map[string]interface{})) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		visit(typed)
+		for _, child := range typed {
+			walkSchema(child, visit)
+		}
+	case []interface{}:
+		for _, child := range typed {
+			walkSchema(child, visit)
+		}
+	}
+}
+
+// schemaName returns the short name of a schema, like
+// "1.0.0-pre/offer".
+func schemaName(id string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(id, schemaBase), ".json")
+}
+
+// writeSchemaDocs writes Markdown reference docs for every schema.
+func writeSchemaDocs(w io.Writer, registry *schemaRegistry) error {
+	var b strings.Builder
+	b.WriteString("# License Zero Schemas\n")
+	for _, id := range registry.ids() {
+		schema := registry.parsed(id)
+		b.WriteString("\n## " + schemaName(id) + "\n\n")
+		if title, ok := schema["title"].(string); ok {
+			b.WriteString(capitalize(title) + ".\n\n")
+		}
+		for _, key := range []string{"comment", "$comment", "description"} {
+			if text, ok := schema[key].(string); ok {
+				b.WriteString(text + "\n\n")
+			}
+		}
+		b.WriteString("`$id`: `" + id + "`\n\n")
+		b.WriteString("Type: " + describeSchemaType(id, schema) + "\n")
+		if details := describeSchemaDetails(schema); details != "" {
+			b.WriteString("\n" + details + "\n")
+		}
+		rows := propertyRows(id, "", schema)
+		if len(rows) != 0 {
+			b.WriteString("\n| Property | Required | Type | Description |\n")
+			b.WriteString("| --- | --- | --- | --- |\n")
+			for _, row := range rows {
+				b.WriteString(row + "\n")
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// propertyRows returns Markdown table rows for the properties of a
+// schema object, and of the objects it contains.
+func propertyRows(id, prefix string, schema map[string]interface{}) (rows []string) {
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		rows = append(rows, propertyRows(id, prefix+"[]", items)...)
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		requiredCell := "no"
+		if required[name] {
+			requiredCell = "yes"
+		}
+		description := ""
+		if title, ok := property["title"].(string); ok {
+			description = capitalize(title) + "."
+		}
+		if details := describeSchemaDetails(property); details != "" {
+			if description != "" {
+				description += " "
+			}
+			description += details
+		}
+		rows = append(rows, "| `"+path+"` | "+requiredCell+" | "+
+			describeSchemaType(id, property)+" | "+escapeTableCell(description)+" |")
+		rows = append(rows, propertyRows(id, path, property)...)
+	}
+	if patterns, ok := schema["patternProperties"].(map[string]interface{}); ok {
+		keys := make([]string, 0, len(patterns))
+		for pattern := range patterns {
+			keys = append(keys, pattern)
+		}
+		sort.Strings(keys)
+		for _, pattern := range keys {
+			property, ok := patterns[pattern].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			path := "/" + pattern + "/"
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			description := ""
+			if title, ok := property["title"].(string); ok {
+				description = capitalize(title) + "."
+			}
+			rows = append(rows, "| `"+path+"` | no | "+
+				describeSchemaType(id, property)+" | "+escapeTableCell(description)+" |")
+		}
+	}
+	return
+}
+
+// describeSchemaType describes the type of a schema in Markdown,
+// linking to referenced schemas.
+func describeSchemaType(id string, schema map[string]interface{}) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := schemaName(resolveReference(id, ref))
+		return "[" + name + "](#" + markdownAnchor(name) + ")"
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		if len(enum) > 10 {
+			return "one of " + strconv.Itoa(len(enum)) + " values"
+		}
+		values := make([]string, len(enum))
+		for index, value := range enum {
+			values[index] = "`" + jsonText(value) + "`"
+		}
+		return "one of " + strings.Join(values, ", ")
+	}
+	typeName, _ := schema["type"].(string)
+	if typeName == "array" {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			return "array of " + describeSchemaType(id, items)
+		}
+	}
+	if typeName == "" {
+		return "any"
+	}
+	return typeName
+}
+
+// describeSchemaDetails describes formats, patterns, limits, and
+// examples in a schema.
+func describeSchemaDetails(schema map[string]interface{}) string {
+	var details []string
+	if format, ok := schema["format"].(string); ok {
+		details = append(details, "Format: "+format+".")
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		details = append(details, "Pattern: `"+pattern+"`.")
+	}
+	for _, limit := range []struct{ key, label string }{
+		{"minLength", "Minimum length"},
+		{"maxLength", "Maximum length"},
+		{"minimum", "Minimum"},
+		{"maximum", "Maximum"},
+	} {
+		if value, ok := schema[limit.key]; ok {
+			details = append(details, limit.label+": "+jsonText(value)+".")
+		}
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) != 0 {
+		values := make([]string, len(examples))
+		for index, value := range examples {
+			values[index] = "`" + jsonText(value) + "`"
+		}
+		details = append(details, "Examples: "+strings.Join(values, ", ")+".")
+	}
+	return strings.Join(details, " ")
+}
+
+func jsonText(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func capitalize(text string) string {
+	if text == "" {
+		return text
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
+
+func escapeTableCell(text string) string {
+	return strings.Replace(text, "|", "\\|", -1)
+}
+
+// markdownAnchor returns the anchor that common Markdown renderers
+// give a heading.
+func markdownAnchor(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchemaReferencesResolve(t *testing.T) {
+	if unresolved := schemas.unresolvedReferences(); len(unresolved) != 0 {
+		t.Errorf("unresolved references: %v", unresolved)
+	}
+	dangling := newSchemaRegistry(price1_0_0PreSchema)
+	unresolved := dangling.unresolvedReferences()
+	if len(unresolved) != 1 || !strings.HasSuffix(unresolved[0], ": currency.json") {
+		t.Errorf("found %v", unresolved)
+	}
+}
+
+func TestSchemaGet(t *testing.T) {
+	for _, name := range []string{
+		"offer",
+		"offer.json",
+		"1.0.0-pre/offer",
+		"https://schemas.licensezero.com/1.0.0-pre/offer.json",
+	} {
+		env, stdout, stderr := newTestEnvironment("", "", nil)
+		if run([]string{"schema", "get", name}, env) != 0 {
+			t.Fatalf("%s: %s", name, stderr.String())
+		}
+		var schema struct {
+			ID string `json:"$id"`
+		}
+		err := json.Unmarshal(stdout.Bytes(), &schema)
+		if err != nil || schema.ID != schemaID("1.0.0-pre", "offer") {
+			t.Errorf("%s: printed %q, %v", name, schema.ID, err)
+		}
+	}
+	env, _, _ := newTestEnvironment("", "", nil)
+	if run([]string{"schema", "get", "nonexistent"}, env) == 0 {
+		t.Error("printed nonexistent schema")
+	}
+}
+
+func TestSchemaDocs(t *testing.T) {
+	env, stdout, stderr := newTestEnvironment("", "", nil)
+	if run([]string{"schema", "docs"}, env) != 0 {
+		t.Fatal(stderr.String())
+	}
+	for _, expected := range []string{
+		"## 1.0.0-pre/price\n\nPrice.\n",
+		"| `currency` | yes | [1.0.0-pre/currency](#100-precurrency) | Purchase price currency code. Examples: `\"USD\"`. |",
+		"| `offers[].offerID` | yes | string | UUIDv4 offer identifier. Format: uuid. |",
+	} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("missing %q", expected)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/xeipuuv/gojsonschema"
+	"sync"
+)
+
+const schemaBase = "https://schemas.licensezero.com/"
+
+// schemaVersions lists the versions of the schemas, newest first.
+var schemaVersions = []string{"1.0.0-pre"}
+
+// schemaID returns the $id of a version of a schema.
+func schemaID(version, name string) string {
+	return schemaBase + version + "/" + name + ".json"
+}
+
+// schemas holds every schema the CLI knows.
+var schemas = newSchemaRegistry(
+	artifact1_0_0PreSchema,
+	currency1_0_0PreSchema,
+	jurisdiction1_0_0PreSchema,
+	key1_0_0PreSchema,
+	licensee1_0_0PreSchema,
+	name1_0_0PreSchema,
+	offer1_0_0PreSchema,
+	price1_0_0PreSchema,
+	receipt1_0_0PreSchema,
+	signature1_0_0PreSchema,
+	time1_0_0PreSchema,
+	url1_0_0PreSchema,
+)
+
+// schemaRegistry compiles schemas by $id, once each, and is safe for
+// concurrent use.
+type schemaRegistry struct {
+	sources  map[string]string
+	mutex    sync.Mutex
+	compiled map[string]*compiledSchema
+}
+
+type compiledSchema struct {
+	once   sync.Once
+	schema *gojsonschema.Schema
+	err    error
+}
+
+func newSchemaRegistry(sources ...string) *schemaRegistry {
+	registry := &schemaRegistry{
+		sources:  make(map[string]string),
+		compiled: make(map[string]*compiledSchema),
+	}
+	for _, source := range sources {
+		var header struct {
+			ID string `json:"$id"`
+		}
+		err := json.Unmarshal([]byte(source), &header)
+		if err != nil || header.ID == "" {
+			panic("schema without $id")
+		}
+		if _, duplicate := registry.sources[header.ID]; duplicate {
+			panic("duplicate schema " + header.ID)
+		}
+		registry.sources[header.ID] = source
+	}
+	return registry
+}
+
+// get returns the compiled schema with an $id.
+func (r *schemaRegistry) get(id string) (*gojsonschema.Schema, error) {
+	if _, ok := r.sources[id]; !ok {
+		return nil, errors.New("unknown schema " + id)
+	}
+	r.mutex.Lock()
+	entry, ok := r.compiled[id]
+	if !ok {
+		entry = &compiledSchema{}
+		r.compiled[id] = entry
+	}
+	r.mutex.Unlock()
+	entry.once.Do(func() {
+		entry.schema, entry.err = r.compile(id)
+	})
+	return entry.schema, entry.err
+}
+
+// compile compiles a schema with every other schema available to
+// resolve references.
+func (r *schemaRegistry) compile(id string) (*gojsonschema.Schema, error) {
+	loader := gojsonschema.NewSchemaLoader()
+	for other, source := range r.sources {
+		if other == id {
+			continue
+		}
+		err := loader.AddSchemas(gojsonschema.NewStringLoader(source))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return loader.Compile(gojsonschema.NewStringLoader(r.sources[id]))
+}
+
+// validate validates parsed JSON data against the schema with an $id.
+func (r *schemaRegistry) validate(id string, unstructured interface{}) (*gojsonschema.Result, error) {
+	schema, err := r.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Validate(gojsonschema.NewGoLoader(unstructured))
+}
+
+// valid reports whether parsed JSON data conforms to the schema with
+// an $id.
+func (r *schemaRegistry) valid(id string, unstructured interface{}) bool {
+	result, err := r.validate(id, unstructured)
+	return err == nil && result.Valid()
+}
+
+var errUnknownSchema = errors.New("unknown schema")
+
+// detectVersion finds the version of a schema that parsed JSON data
+// conforms to. Data may declare its schema with a "$schema" property,
+// which is removed from the data returned. Otherwise, each version is
+// tried, newest first.
+func detectVersion(name string, unstructured interface{}) (version string, data interface{}, err error) {
+	if object, ok := unstructured.(map[string]interface{}); ok {
+		if declared, ok := object["$schema"].(string); ok {
+			for _, version := range schemaVersions {
+				if declared != schemaID(version, name) {
+					continue
+				}
+				data := make(map[string]interface{}, len(object))
+				for key, value := range object {
+					if key != "$schema" {
+						data[key] = value
+					}
+				}
+				result, err := schemas.validate(declared, data)
+				if err != nil {
+					return "", nil, err
+				}
+				if !result.Valid() {
+					return "", nil, schemaErrors(result)
+				}
+				return version, data, nil
+			}
+			return "", nil, errors.New("unknown schema " + declared)
+		}
+	}
+	for _, version := range schemaVersions {
+		if schemas.valid(schemaID(version, name), unstructured) {
+			return version, unstructured, nil
+		}
+	}
+	return "", nil, errUnknownSchema
+}
+
+// schemaErrors combines validation errors into one.
+func schemaErrors(result *gojsonschema.Result) error {
+	message := ""
+	for index, resultError := range result.Errors() {
+		if index > 0 {
+			message += "; "
+		}
+		message += resultError.String()
+	}
+	return errors.New(message)
+}
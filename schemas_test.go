@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestSchemasCompile(t *testing.T) {
+	for id := range schemas.sources {
+		if _, err := schemas.get(id); err != nil {
+			t.Errorf("%s: %v", id, err)
+		}
+	}
+	if _, err := schemas.get(schemaID("0.0.0", "offer")); err == nil {
+		t.Error("got unknown schema")
+	}
+}
+
+func TestSchemasConcurrent(t *testing.T) {
+	registry := newSchemaRegistry(
+		offer1_0_0PreSchema,
+		currency1_0_0PreSchema,
+		price1_0_0PreSchema,
+		signature1_0_0PreSchema,
+		url1_0_0PreSchema,
+	)
+	id := schemaID("1.0.0-pre", "offer")
+	compiled := make(chan interface{}, 10)
+	var group sync.WaitGroup
+	for i := 0; i < cap(compiled); i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			schema, err := registry.get(id)
+			if err != nil {
+				t.Error(err)
+			}
+			compiled <- schema
+		}()
+	}
+	group.Wait()
+	close(compiled)
+	first := <-compiled
+	for schema := range compiled {
+		if schema != first {
+			t.Fatal("compiled more than once")
+		}
+	}
+}
+
+func TestDetectVersion(t *testing.T) {
+	var declared interface{}
+	err := json.Unmarshal([]byte(`{
+  "$schema": "https://schemas.licensezero.com/1.0.0-pre/artifact.json",
+  "offers": [
+    {
+      "api": "https://api.licensezero.com",
+      "offerID": "36fce1e2-5e96-41fc-8776-4e632b546d96"
+    }
+  ]
+}`), &declared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, data, err := detectVersion("artifact", declared)
+	if err != nil || version != "1.0.0-pre" {
+		t.Fatalf("detected %q, %v", version, err)
+	}
+	if _, ok := data.(map[string]interface{})["$schema"]; ok {
+		t.Error("kept $schema")
+	}
+	artifact, err := ParseArtifact(declared)
+	if err != nil || len(artifact.Offers()) != 1 {
+		t.Errorf("parsed %v, %v", artifact, err)
+	}
+
+	declared.(map[string]interface{})["$schema"] = "https://schemas.licensezero.com/9.9.9/artifact.json"
+	if _, err := ParseArtifact(declared); err == nil {
+		t.Error("parsed unknown declared version")
+	}
+}
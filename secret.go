@@ -0,0 +1,347 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// sealed holds data encrypted with a key derived from a passphrase.
+type sealed struct {
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+const (
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	saltLength   = 32
+	nonceLength  = 24
+	secretLength = 32
+)
+
+const passphraseEnvironmentVariable = "LICENSEZERO_PASSPHRASE"
+
+func seal(passphrase string, plaintext []byte) (*sealed, error) {
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	var nonce [nonceLength]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := secretbox.Seal(nil, plaintext, &nonce, key)
+	return &sealed{
+		Salt:       hex.EncodeToString(salt),
+		Nonce:      hex.EncodeToString(nonce[:]),
+		Ciphertext: hex.EncodeToString(ciphertext),
+	}, nil
+}
+
+func (s *sealed) open(passphrase string) ([]byte, error) {
+	salt, err := hex.DecodeString(s.Salt)
+	if err != nil {
+		return nil, errors.New("invalid salt")
+	}
+	nonceBytes, err := hex.DecodeString(s.Nonce)
+	if err != nil || len(nonceBytes) != nonceLength {
+		return nil, errors.New("invalid nonce")
+	}
+	var nonce [nonceLength]byte
+	copy(nonce[:], nonceBytes)
+	ciphertext, err := hex.DecodeString(s.Ciphertext)
+	if err != nil {
+		return nil, errors.New("invalid ciphertext")
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, ok := secretbox.Open(nil, ciphertext, &nonce, key)
+	if !ok {
+		return nil, errors.New("wrong passphrase")
+	}
+	return plaintext, nil
+}
+
+func deriveKey(passphrase string, salt []byte) (*[secretLength]byte, error) {
+	derived, err := scrypt.Key(
+		[]byte(passphrase), salt,
+		scryptN, scryptR, scryptP, secretLength,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var key [secretLength]byte
+	copy(key[:], derived)
+	return &key, nil
+}
+
+// readPassphrase reads the passphrase from the environment, if set,
+// or else prompts for it.
+func readPassphrase(env *environment, prompt string) (string, error) {
+	return readSecret(env, passphraseEnvironmentVariable, prompt)
+}
+
+// readNewPassphrase reads a new passphrase from an environment
+// variable, if set, or else prompts for it twice.
+func readNewPassphrase(env *environment, variable string) (string, error) {
+	if fromEnvironment := env.Getenv(variable); fromEnvironment != "" {
+		return fromEnvironment, nil
+	}
+	passphrase, err := readSecret(env, "", "New passphrase")
+	if err != nil {
+		return "", err
+	}
+	confirmation, err := readSecret(env, "", "Confirm passphrase")
+	if err != nil {
+		return "", err
+	}
+	if confirmation != passphrase {
+		return "", errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func readSecret(env *environment, variable string, prompt string) (secret string, err error) {
+	if variable != "" {
+		if fromEnvironment := env.Getenv(variable); fromEnvironment != "" {
+			return fromEnvironment, nil
+		}
+	}
+	fmt.Fprint(env.Stderr, prompt+": ")
+	if file, ok := env.Stdin.(*os.File); ok && terminal.IsTerminal(int(file.Fd())) {
+		var data []byte
+		data, err = terminal.ReadPassword(int(file.Fd()))
+		fmt.Fprintln(env.Stderr)
+		secret = string(data)
+	} else {
+		secret, err = env.readLine()
+	}
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", errors.New("empty " + strings.ToLower(prompt))
+	}
+	return secret, nil
+}
+
+// readSealedFiles reads every file in the configuration directory
+// that holds sealed data: signing keys and account tokens. It skips
+// files that aren't JSON, like editor backups and temporary files,
+// and returns errors for JSON files it can't read.
+func readSealedFiles(configPath string) (keys map[string]*signingKeyFile, accounts map[string]*accountRecord, errors []error, err error) {
+	keys = make(map[string]*signingKeyFile)
+	accounts = make(map[string]*accountRecord)
+	keyEntries, err := listSealedFiles(path.Join(configPath, "keys"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, name := range keyEntries {
+		name = strings.TrimSuffix(name, ".json")
+		filePath := signingKeyPath(configPath, name)
+		file, err := readSigningKeyFile(configPath, name)
+		if err != nil {
+			errors = append(errors, &fileError{filePath, unwrapPathError(err)})
+			continue
+		}
+		keys[filePath] = file
+	}
+	accountEntries, err := listSealedFiles(path.Join(configPath, "accounts"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, name := range accountEntries {
+		filePath := path.Join(configPath, "accounts", name)
+		account, err := readAccountRecord(filePath)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		accounts[filePath] = account
+	}
+	return keys, accounts, errors, nil
+}
+
+// listSealedFiles lists the names of JSON files in a directory, if it
+// exists, leaving out hidden files.
+func listSealedFiles(directory string) (names []string, err error) {
+	entries, err := ioutil.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// readStorePassphrase reads the passphrase for sealing a new secret.
+// If secrets are already stored, the passphrase must open them.
+// Otherwise, this is a new passphrase.
+func readStorePassphrase(env *environment) (string, error) {
+	keys, accounts, _, err := readSealedFiles(env.Config)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 && len(accounts) == 0 {
+		return readNewPassphrase(env, passphraseEnvironmentVariable)
+	}
+	passphrase, err := readPassphrase(env, "Passphrase")
+	if err != nil {
+		return "", err
+	}
+	err = checkPassphrase(env.Config, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return passphrase, nil
+}
+
+// checkPassphrase ensures that new secrets get sealed with the same
+// passphrase as existing secrets, so that one passphrase opens all.
+func checkPassphrase(configPath string, passphrase string) error {
+	keys, accounts, _, err := readSealedFiles(configPath)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		_, err := key.PrivateKey.open(passphrase)
+		return err
+	}
+	for _, account := range accounts {
+		if account.SealedToken != nil {
+			_, err := account.SealedToken.open(passphrase)
+			return err
+		}
+	}
+	return nil
+}
+
+const rekeyUsage = `Change the passphrase for stored keys and tokens.
+
+Usage:
+  licensezero rekey
+
+The current passphrase is read from ` + passphraseEnvironmentVariable + ` or prompted for.
+The new passphrase is read from ` + newPassphraseEnvironmentVariable + ` or prompted for.
+
+Tokens saved before encryption was supported get encrypted, too.
+`
+
+const newPassphraseEnvironmentVariable = "LICENSEZERO_NEW_PASSPHRASE"
+
+var rekeyCommand = subcommand{
+	Summary: "Change the passphrase for stored secrets.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("rekey", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, rekeyUsage) }
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 {
+			flagSet.Usage()
+			return 1
+		}
+		keys, accounts, unreadable, err := readSealedFiles(env.Config)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read stored secrets:", err)
+			return 1
+		}
+		// Changing the passphrase for only some files would leave the
+		// others sealed with the old one.
+		if len(unreadable) != 0 {
+			for _, err := range unreadable {
+				fmt.Fprintln(env.Stderr, "Could not read", err)
+			}
+			return 1
+		}
+		if len(keys) == 0 && len(accounts) == 0 {
+			fmt.Fprintln(env.Stderr, "No stored keys or tokens.")
+			return 1
+		}
+		oldPassphrase, err := readPassphrase(env, "Current passphrase")
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read passphrase:", err)
+			return 1
+		}
+		// Open everything before changing anything.
+		privateKeys := make(map[string][]byte)
+		for filePath, key := range keys {
+			opened, err := key.PrivateKey.open(oldPassphrase)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "Could not open %s: %v\n", filePath, err)
+				return 1
+			}
+			privateKeys[filePath] = opened
+		}
+		for filePath, account := range accounts {
+			err := account.unlock(oldPassphrase)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "Could not open %s: %v\n", filePath, err)
+				return 1
+			}
+		}
+		newPassphrase, err := readNewPassphrase(env, newPassphraseEnvironmentVariable)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read new passphrase:", err)
+			return 1
+		}
+		for filePath, key := range keys {
+			sealedKey, err := seal(newPassphrase, privateKeys[filePath])
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not encrypt key:", err)
+				return 1
+			}
+			key.PrivateKey = sealedKey
+			data, err := json.MarshalIndent(key, "", "  ")
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not encode key:", err)
+				return 1
+			}
+			err = writeFileAtomically(filePath, data, 0600)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "Could not write %s: %v\n", filePath, err)
+				return 1
+			}
+		}
+		for filePath, account := range accounts {
+			data, err := account.seal(newPassphrase)
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not encrypt token:", err)
+				return 1
+			}
+			err = writeFileAtomically(filePath, data, 0600)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "Could not write %s: %v\n", filePath, err)
+				return 1
+			}
+		}
+		fmt.Fprintf(env.Stdout, "Changed passphrase for %d keys and %d accounts.\n", len(keys), len(accounts))
+		return 0
+	},
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+)
+
+// signingKeyFile is the on-disk form of an ed25519 keypair, with the
+// private key sealed by the user's passphrase.
+type signingKeyFile struct {
+	PublicKey  string  `json:"publicKey"`
+	PrivateKey *sealed `json:"privateKey"`
+}
+
+var validKeyName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func signingKeyPath(configPath string, name string) string {
+	return path.Join(configPath, "keys", name+".json")
+}
+
+func writeSigningKey(configPath string, name string, publicKey ed25519.PublicKey, privateKey ed25519.PrivateKey, passphrase string) error {
+	sealedKey, err := seal(passphrase, privateKey)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(signingKeyFile{
+		PublicKey:  hex.EncodeToString(publicKey),
+		PrivateKey: sealedKey,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path.Join(configPath, "keys"), 0700)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(signingKeyPath(configPath, name), data, 0600)
+}
+
+func readSigningKeyFile(configPath string, name string) (*signingKeyFile, error) {
+	data, err := ioutil.ReadFile(signingKeyPath(configPath, name))
+	if err != nil {
+		return nil, err
+	}
+	var file signingKeyFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+	if file.PrivateKey == nil {
+		return nil, errors.New("missing private key")
+	}
+	return &file, nil
+}
+
+func readSigningKey(configPath string, name string, passphrase string) (ed25519.PrivateKey, error) {
+	file, err := readSigningKeyFile(configPath, name)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := file.PrivateKey.open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(opened) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key")
+	}
+	privateKey := ed25519.PrivateKey(opened)
+	if hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)) != file.PublicKey {
+		return nil, errors.New("private key does not match public key")
+	}
+	return privateKey, nil
+}
+
+const keygenUsage = `Generate an ed25519 keypair for signing receipts.
+
+Usage:
+  licensezero keygen [--name NAME] [--force]
+
+The private key is encrypted with a passphrase, read from ` + passphraseEnvironmentVariable + `
+or prompted for. Keys and account tokens share one passphrase.
+`
+
+var keygenCommand = subcommand{
+	Summary: "Generate a receipt signing keypair.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("keygen", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, keygenUsage) }
+		name := flagSet.String("name", "default", "")
+		force := flagSet.Bool("force", false, "")
+		if flagSet.Parse(args) != nil || flagSet.NArg() != 0 {
+			flagSet.Usage()
+			return 1
+		}
+		if !validKeyName.MatchString(*name) {
+			fmt.Fprintln(env.Stderr, "Invalid key name:", *name)
+			return 1
+		}
+		if _, err := os.Stat(signingKeyPath(env.Config, *name)); err == nil && !*force {
+			fmt.Fprintf(env.Stderr, "Key %q already exists. Use --force to replace it.\n", *name)
+			return 1
+		}
+		passphrase, err := readStorePassphrase(env)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not read passphrase:", err)
+			return 1
+		}
+		publicKey, privateKey, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not generate key:", err)
+			return 1
+		}
+		err = writeSigningKey(env.Config, *name, publicKey, privateKey, passphrase)
+		if err != nil {
+			fmt.Fprintln(env.Stderr, "Could not write key:", err)
+			return 1
+		}
+		fmt.Fprintln(env.Stdout, hex.EncodeToString(publicKey))
+		return 0
+	},
+}
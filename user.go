@@ -20,28 +20,32 @@ func ReadAccounts(configPath string) (accounts []Account, errors []error, err er
 	for _, entry := range entries {
 		name := entry.Name()
 		filePath := path.Join(configPath, "accounts", name)
-		receipt, err := readAccount(filePath)
+		account, err := readAccount(filePath)
 		if err != nil {
 			errors = append(errors, err)
 		} else {
-			accounts = append(accounts, *receipt)
+			accounts = append(accounts, account)
 		}
 	}
 	return
 }
 
-func readAccount(filePath string) (account *Account, err error) {
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
-	err = json.Unmarshal(data, &account)
-	return
+func readAccount(filePath string) (Account, error) {
+	return readAccountRecord(filePath)
 }
 
 // ReadReceipts reads all receipts in the configuration directory.
 func ReadReceipts(configPath string) (receipts []Receipt, errors []error, err error) {
-	directoryPath := path.Join(configPath, "receipts")
+	return readReceiptsIn(path.Join(configPath, "receipts"))
+}
+
+// ReadLegacyReceipts reads receipts migrate converted from the
+// previous CLI's licenses.
+func ReadLegacyReceipts(configPath string) (receipts []Receipt, errors []error, err error) {
+	return readReceiptsIn(path.Join(configPath, legacyReceiptsDirectory))
+}
+
+func readReceiptsIn(directoryPath string) (receipts []Receipt, errors []error, err error) {
 	entries, directoryReadError := ioutil.ReadDir(directoryPath)
 	if directoryReadError != nil {
 		if os.IsNotExist(directoryReadError) {
@@ -51,7 +55,7 @@ func ReadReceipts(configPath string) (receipts []Receipt, errors []error, err er
 	}
 	for _, entry := range entries {
 		name := entry.Name()
-		filePath := path.Join(configPath, "receipts", name)
+		filePath := path.Join(directoryPath, name)
 		receipt, err := readReceipt(filePath)
 		if err != nil {
 			errors = append(errors, err)
@@ -70,7 +74,11 @@ func readReceipt(filePath string) (Receipt, error) {
 	var unstructured interface{}
 	err = json.Unmarshal(data, &unstructured)
 	if err != nil {
-		return nil, err
+		return nil, &fileError{filePath, err}
+	}
+	receipt, err := ParseReceipt(unstructured)
+	if err != nil {
+		return nil, &fileError{filePath, err}
 	}
-	return ParseReceipt(unstructured)
+	return receipt, nil
 }
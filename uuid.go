@@ -0,0 +1,26 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+var validUUID = regexp.MustCompile(
+	`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`,
+)
+
+// newUUID returns a random, version 4 UUID.
+func newUUID() (string, error) {
+	var bytes [16]byte
+	if _, err := io.ReadFull(rand.Reader, bytes[:]); err != nil {
+		return "", err
+	}
+	bytes[6] = (bytes[6] & 0x0f) | 0x40
+	bytes[8] = (bytes[8] & 0x3f) | 0x80
+	return fmt.Sprintf(
+		"%x-%x-%x-%x-%x",
+		bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:16],
+	), nil
+}
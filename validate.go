@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const validateUsage = `Check artifact metadata, offers, and receipts.
+
+Usage:
+  licensezero validate [--json] <file>...
+
+Flags:
+  --json    print results as JSON
+
+Tells whether each file is artifact metadata, like licensezero.json,
+an offer, or a receipt, checks it against the schema, and checks
+receipt signatures. Files may name the schema they follow with a
+"$schema" property.
+`
+
+var validateCommand = subcommand{
+	Summary: "Check artifact metadata, offers, and receipts.",
+	Handler: func(args []string, env *environment) int {
+		flagSet := flag.NewFlagSet("validate", flag.ContinueOnError)
+		flagSet.SetOutput(env.Stderr)
+		flagSet.Usage = func() { fmt.Fprint(env.Stderr, validateUsage) }
+		asJSON := flagSet.Bool("json", false, "")
+		files, err := parseInterspersed(flagSet, args)
+		if err != nil || len(files) == 0 {
+			flagSet.Usage()
+			return 1
+		}
+		var results []validation
+		failed := false
+		for _, file := range files {
+			filePath := file
+			if !path.IsAbs(filePath) {
+				filePath = path.Join(env.CWD, filePath)
+			}
+			result := validateFile(filePath)
+			result.Path = file
+			if !result.Valid {
+				failed = true
+			}
+			results = append(results, result)
+		}
+		if *asJSON {
+			data, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				fmt.Fprintln(env.Stderr, "Could not encode results:", err)
+				return 1
+			}
+			fmt.Fprintln(env.Stdout, string(data))
+		} else {
+			for _, result := range results {
+				if result.Valid {
+					fmt.Fprintf(env.Stdout, "%s: valid %s\n", result.Path, result.Type)
+					continue
+				}
+				for _, problem := range result.Problems {
+					fmt.Fprintln(env.Stdout, problem.describe(result.Path))
+				}
+			}
+		}
+		if failed {
+			return 1
+		}
+		return 0
+	},
+}
+
+// validation is the result of checking a file.
+type validation struct {
+	Path     string    `json:"path"`
+	Type     string    `json:"type,omitempty"`
+	Valid    bool      `json:"valid"`
+	Problems []problem `json:"problems"`
+	data     []byte
+}
+
+// problem describes something wrong with a file. Line and Column count
+// from 1, and are 0 when the problem has no location.
+type problem struct {
+	Pointer string `json:"pointer"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p problem) describe(filePath string) string {
+	location := filePath
+	if p.Line != 0 {
+		location += ":" + strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	}
+	if p.Pointer != "" {
+		return location + ": " + p.Pointer + ": " + p.Message
+	}
+	return location + ": " + p.Message
+}
+
+// documentTypes are the schemas of files validate can check.
+var documentTypes = []string{"artifact", "offer", "receipt"}
+
+// validateFile checks a file against the schema for its type.
+func validateFile(filePath string) (result validation) {
+	result.Problems = []problem{}
+	fail := func(pointer string, offset int, message string) {
+		p := problem{Pointer: pointer, Message: message}
+		if offset >= 0 {
+			p.Line, p.Column = lineAndColumn(result.data, offset)
+		}
+		result.Problems = append(result.Problems, p)
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		fail("", -1, err.Error())
+		return
+	}
+	result.data = data
+	var unstructured interface{}
+	err = json.Unmarshal(data, &unstructured)
+	if err != nil {
+		offset := -1
+		if syntaxError, ok := err.(*json.SyntaxError); ok {
+			// The offset follows the offending character.
+			offset = int(syntaxError.Offset) - 1
+		}
+		fail("", offset, err.Error())
+		return
+	}
+	locations, err := jsonLocations(data)
+	if err != nil {
+		fail("", -1, err.Error())
+		return
+	}
+	locate := func(pointer string) int {
+		if offset, ok := locations[pointer]; ok {
+			return offset
+		}
+		return -1
+	}
+	documentType, id, err := detectDocumentType(unstructured)
+	if err != nil {
+		fail("", locate(""), err.Error())
+		return
+	}
+	result.Type = documentType
+	checked := unstructured
+	if object, ok := unstructured.(map[string]interface{}); ok {
+		if _, declared := object["$schema"]; declared {
+			copied := make(map[string]interface{}, len(object))
+			for key, value := range object {
+				if key != "$schema" {
+					copied[key] = value
+				}
+			}
+			checked = copied
+		}
+	}
+	schemaResult, err := schemas.validate(id, checked)
+	if err != nil {
+		fail("", -1, err.Error())
+		return
+	}
+	for _, resultError := range schemaResult.Errors() {
+		pointer := contextPointer(resultError.Context().String("\x00"))
+		fail(pointer, locate(pointer), resultError.Description())
+	}
+	if len(result.Problems) == 0 && documentType == "receipt" {
+		receipt, err := ParseReceipt(unstructured)
+		if err == nil {
+			err = receipt.ValidateSignature()
+		}
+		if err != nil {
+			fail("/signature", locate("/signature"), err.Error())
+		}
+	}
+	result.Valid = len(result.Problems) == 0
+	return
+}
+
+// detectDocumentType returns the type of a document and the $id of the
+// schema to check it against. Documents that name a schema with
+// "$schema" get that one. Otherwise, the first type and version the
+// document conforms to wins, falling back to a guess by properties.
+func detectDocumentType(unstructured interface{}) (documentType string, id string, err error) {
+	object, ok := unstructured.(map[string]interface{})
+	if !ok {
+		return "", "", errors.New("not a JSON object")
+	}
+	if declared, ok := object["$schema"].(string); ok {
+		for _, documentType := range documentTypes {
+			for _, version := range schemaVersions {
+				if declared == schemaID(version, documentType) {
+					return documentType, declared, nil
+				}
+			}
+		}
+		return "", "", errors.New("unknown schema " + declared)
+	}
+	for _, documentType := range documentTypes {
+		for _, version := range schemaVersions {
+			id := schemaID(version, documentType)
+			if schemas.valid(id, unstructured) {
+				return documentType, id, nil
+			}
+		}
+	}
+	latest := schemaVersions[0]
+	switch {
+	case object["license"] != nil || object["signature"] != nil && object["key"] != nil:
+		return "receipt", schemaID(latest, "receipt"), nil
+	case object["offers"] != nil:
+		return "artifact", schemaID(latest, "artifact"), nil
+	case object["licensorID"] != nil || object["pricing"] != nil:
+		return "offer", schemaID(latest, "offer"), nil
+	}
+	return "", "", errors.New("not artifact metadata, an offer, or a receipt")
+}
+
+// contextPointer converts a schema validation context, with parts
+// separated by NUL, to a JSON pointer.
+func contextPointer(context string) string {
+	parts := strings.Split(context, "\x00")
+	pointer := ""
+	for _, part := range parts[1:] {
+		pointer += "/" + escapePointer(part)
+	}
+	return pointer
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
+
+// jsonLocations maps the JSON pointer of each value in a document to
+// the offset where the value starts.
+func jsonLocations(data []byte) (map[string]int, error) {
+	locations := make(map[string]int)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	err := locateValues(decoder, data, "", locations)
+	if err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+func locateValues(decoder *json.Decoder, data []byte, pointer string, locations map[string]int) error {
+	locations[pointer] = skipSeparators(data, int(decoder.InputOffset()))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			err = locateValues(decoder, data, pointer+"/"+escapePointer(key.(string)), locations)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	case json.Delim('['):
+		for index := 0; decoder.More(); index++ {
+			err := locateValues(decoder, data, pointer+"/"+strconv.Itoa(index), locations)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	}
+	return err
+}
+
+// skipSeparators skips whitespace, colons, and commas.
+func skipSeparators(data []byte, offset int) int {
+	for offset < len(data) && strings.IndexByte(" \t\r\n:,", data[offset]) != -1 {
+		offset++
+	}
+	return offset
+}
+
+// lineAndColumn finds the line and column of an offset, counting from 1.
+func lineAndColumn(data []byte, offset int) (line, column int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = offset - bytes.LastIndexByte(before, '\n')
+	return
+}
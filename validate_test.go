@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		files := map[string]string{
+			"valid.json": `{
+  "offers": [
+    {
+      "api": "https://api.licensezero.com",
+      "offerID": "36fce1e2-5e96-41fc-8776-4e632b546d96"
+    }
+  ]
+}`,
+			"invalid.json": `{
+  "offers": [
+    {
+      "api": "https://api.licensezero.com",
+      "offerID": "not an ID"
+    }
+  ]
+}`,
+			"broken.json": "{\n  \"offers\": [,\n}",
+		}
+		for name, content := range files {
+			err := ioutil.WriteFile(path.Join(directory, name), []byte(content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		env, stdout, _ := newTestEnvironment(directory, "", nil)
+		if run([]string{"validate", "valid.json"}, env) != 0 {
+			t.Fatal(stdout.String())
+		}
+		if stdout.String() != "valid.json: valid artifact\n" {
+			t.Errorf("output %q", stdout.String())
+		}
+
+		env, stdout, _ = newTestEnvironment(directory, "", nil)
+		if run([]string{"validate", "valid.json", "invalid.json", "broken.json"}, env) == 0 {
+			t.Fatal("validated invalid files")
+		}
+		for _, expected := range []string{
+			"invalid.json:5:18: /offers/0/offerID: ",
+			"broken.json:2:14: ",
+		} {
+			if !strings.Contains(stdout.String(), expected) {
+				t.Errorf("missing %q in output:\n%s", expected, stdout.String())
+			}
+		}
+	})
+}
+
+func TestValidateReceiptJSON(t *testing.T) {
+	WithTestDir(t, func(directory string) {
+		receipt := issueTestReceipt(t, directory, testLicense())
+		receipt.License.Values.OfferID = "00000000-0000-4000-8000-000000000000"
+		data, err := json.MarshalIndent(receipt, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path.Join(directory, "receipt.json"), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, stdout, _ := newTestEnvironment(directory, "", nil)
+		if run([]string{"validate", "--json", "receipt.json"}, env) == 0 {
+			t.Fatal("validated tampered receipt")
+		}
+		var results []validation
+		err = json.Unmarshal(stdout.Bytes(), &results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Type != "receipt" || results[0].Valid ||
+			len(results[0].Problems) != 1 || results[0].Problems[0].Pointer != "/signature" ||
+			results[0].Problems[0].Line == 0 {
+			t.Errorf("results %+v", results)
+		}
+	})
+}